@@ -2,49 +2,133 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
-	"math/rand"
 	"os"
-	"time"
+	"path/filepath"
 
 	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
-	"github.com/Guilherme-De-Marchi/nbody-go/ui"
 )
 
-var (
-	simulConf SimulConfig
-)
+const DEFAULT_CONFIG_PATH = "./config.json"
 
 type SimulConfig struct {
-	GenerationType string         `json:"generation_type,omitempty"`
-	Universe       simul.Universe `json:"universe,omitempty"`
-	RandOpt        simul.RandOpt  `json:"random_options,omitempty"`
-	EditOpt        simul.EditOpt  `json:"edit_options,omitempty"`
+	GenerationType string          `json:"generation_type,omitempty"`
+	Universe       simul.Universe  `json:"universe,omitempty"`
+	RandOpt        simul.RandOpt   `json:"random_options,omitempty"`
+	PrefabOpt      simul.PrefabOpt `json:"prefab_options,omitempty"`
+	EditOpt        simul.EditOpt   `json:"edit_options,omitempty"`
+	OutputOpt      simul.OutputOpt `json:"output_options,omitempty"`
 }
 
+/*
+Usage:
+
+	nbody [--config file] [--state file]
+	nbody run --steps N [--config file] [--state file] [--out file]
+
+Build with the "headless" tag to leave the window (and ebiten) out,
+only the run command is available on this build.
+*/
 func main() {
-	confJ, err := os.ReadFile("./config.json")
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		if err := runHeadless(os.Args[2:]); err != nil {
+			log.Fatal("[RUN ERROR]: ", err)
+		}
+		return
+	}
+
+	confPath := flag.String("config", DEFAULT_CONFIG_PATH, "configuration file")
+	statePath := flag.String("state", "", "saved state to start from")
+	flag.Parse()
+
+	simulConf, err := loadConfig(*confPath, *statePath == "")
 	if err != nil {
-		log.Fatal("[INTERNAL ERROR]: ", err)
-	}
-	json.Unmarshal(confJ, &simulConf)
-
-	var universe *simul.Universe
-	if simulConf.GenerationType == "randomized" {
-		rand.Seed(time.Now().UnixNano())
-		universe = simul.NewRandomUniverse(
-			simulConf.Universe.Size,
-			simul.G,
-			simulConf.RandOpt.MassR,
-			simulConf.RandOpt.RadR,
-			simulConf.RandOpt.ObjectQtt,
-		)
-	} else if simulConf.GenerationType == "prefab" {
-		log.Fatal("Generation type 'prefab' not implemented")
-	} else {
-		log.Fatal("Invalid value for field 'generation_type'")
-	}
-
-	s := simul.NewSimulation(universe, simulConf.RandOpt, simulConf.EditOpt)
-	(*ui.Game)(s).Init()
+		log.Fatal("[CONFIG ERROR]: ", err)
+	}
+	if *statePath != "" {
+		if err := simulConf.applyState(*statePath); err != nil {
+			log.Fatal("[STATE ERROR]: ", err)
+		}
+	}
+
+	if err := runWindow(simulConf); err != nil {
+		log.Fatal("[GAME ERROR]: ", err)
+	}
+}
+
+/*
+Reads the configuration file and, when generate is set, generates
+the objects of its universe. It isn't set when a saved state
+replaces the universe, which is then left uninitialized.
+*/
+func loadConfig(path string, generate bool) (*SimulConfig, error) {
+	confJ, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	simulConf := &SimulConfig{}
+	if err := json.Unmarshal(confJ, simulConf); err != nil {
+		return nil, err
+	}
+	if !generate {
+		return simulConf, nil
+	}
+
+	// Every random value of the universe comes from the seed
+	if err := simulConf.RandOpt.Init(); err != nil {
+		return nil, err
+	}
+	universe := &simulConf.Universe
+	universe.SetSeed(simulConf.RandOpt.Seed)
+	log.Println("[CONFIG] SEED:", simulConf.RandOpt.Seed)
+
+	genType := simulConf.GenerationType
+	_, isModel := simul.Models[genType]
+	if genType == "prefab" {
+		// Scenario paths are relative to the configuration file
+		if sc := simulConf.PrefabOpt.Scenario; sc != "" && !filepath.IsAbs(sc) {
+			simulConf.PrefabOpt.Scenario = filepath.Join(filepath.Dir(path), sc)
+		}
+		objs, err := simulConf.PrefabOpt.GetObjects(universe)
+		if err != nil {
+			return nil, err
+		}
+		universe.AddObjects(objs...)
+	} else if genType != "randomized" && !isModel {
+		return nil, fmt.Errorf("invalid value for field 'generation_type'")
+	}
+
+	// The objects of a model only collide when a policy is set
+	if isModel && universe.CollisionName == "" {
+		universe.CollisionName = simul.DEFAULT_MODEL_COLLISION
+	}
+	if err := universe.Init(); err != nil {
+		return nil, err
+	}
+
+	if genType == "randomized" {
+		universe.AddRandomObjects(simulConf.RandOpt, simulConf.RandOpt.ObjectQtt)
+	} else if isModel {
+		if _, err := universe.AddModelObjects(genType, simulConf.RandOpt); err != nil {
+			return nil, err
+		}
+	}
+	return simulConf, nil
+}
+
+/*
+Replaces the universe and options of the configuration
+by the ones of a saved state
+*/
+func (conf *SimulConfig) applyState(path string) error {
+	st, err := simul.ReadState(path)
+	if err != nil {
+		return err
+	}
+	conf.Universe = *st.Universe
+	conf.RandOpt = st.RandOpt
+	conf.EditOpt = st.EditOpt
+	return nil
 }
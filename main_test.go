@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+)
+
+// Generates every model with the shipped configuration
+func TestShippedConfigModels(t *testing.T) {
+	shipped, err := os.ReadFile(DEFAULT_CONFIG_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range simul.Models {
+		conf := map[string]any{}
+		if err := json.Unmarshal(shipped, &conf); err != nil {
+			t.Fatal(err)
+		}
+		conf["generation_type"] = name
+		conf["random_options"].(map[string]any)["object_quantity"] = 200
+		conf["random_options"].(map[string]any)["seed"] = 1
+		path := filepath.Join(t.TempDir(), "config.json")
+		confJ, err := json.Marshal(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, confJ, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		simulConf, err := loadConfig(path, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := &simulConf.Universe
+		qtt := len(u.Objects)
+		for i := 0; i < 20; i++ {
+			u.Step()
+		}
+		if len(u.Objects) != qtt {
+			t.Errorf("%s: %d objects left of %d after 20 steps", name, len(u.Objects), qtt)
+		}
+	}
+}
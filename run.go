@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+)
+
+/*
+Steps the universe of the configuration without opening a window.
+The trajectory is written when an output path is set by the
+flags or by the configuration output options.
+*/
+func runHeadless(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	steps := fs.Int("steps", 1000, "amount of steps to simulate")
+	confPath := fs.String("config", DEFAULT_CONFIG_PATH, "configuration file")
+	statePath := fs.String("state", "", "saved state to start from")
+	out := fs.String("out", "", "trajectory output file (.csv, .jsonl or .bin)")
+	format := fs.String("format", "", "trajectory format (csv, jsonl or binary), taken from the file extension by default")
+	every := fs.Int("every", 0, "write the trajectory once every N steps")
+	progress := fs.Int("progress", 100, "print the progress once every N steps")
+	fs.Parse(args)
+
+	if *steps <= 0 || *every < 0 || *progress <= 0 {
+		return fmt.Errorf("steps and progress must be positive")
+	}
+
+	simulConf, err := loadConfig(*confPath, *statePath == "")
+	if err != nil {
+		return err
+	}
+	if *statePath != "" {
+		if err := simulConf.applyState(*statePath); err != nil {
+			return err
+		}
+	}
+	u := &simulConf.Universe
+
+	outOpt := simulConf.OutputOpt
+	if *out != "" {
+		outOpt.Path = *out
+	}
+	if *format != "" {
+		outOpt.Format = *format
+	}
+	if *every != 0 {
+		outOpt.Every = *every
+	}
+
+	var rec *simul.Recorder
+	if outOpt.Path != "" {
+		if rec, err = simul.NewRecorder(outOpt); err != nil {
+			return err
+		}
+		defer rec.Close()
+		if err := rec.Record(u); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[RUN] %d objects, %d steps, dt %v\n", len(u.Objects), *steps, u.Dt)
+	logDiagnostics(u)
+	start := time.Now()
+	for i := 1; i <= *steps; i++ {
+		u.Step()
+
+		if rec != nil {
+			if err := rec.Record(u); err != nil {
+				return err
+			}
+		}
+		if i%*progress == 0 || i == *steps {
+			log.Printf(
+				"[RUN] step %d/%d  time %v  objects %d  %.1f steps/s\n",
+				i, *steps, u.Time, len(u.Objects), float64(i)/time.Since(start).Seconds(),
+			)
+			logDiagnostics(u)
+		}
+	}
+
+	if rec != nil {
+		if err := rec.Close(); err != nil {
+			return err
+		}
+		log.Println("[RUN] trajectory written to", rec.Path)
+	}
+	return nil
+}
+
+// Prints the conserved quantities and their drift since the start
+func logDiagnostics(u *simul.Universe) {
+	d, drift := u.GetDrift()
+	log.Printf(
+		"[RUN]   energy %.6e (drift %.2e)  momentum %.3e %.3e %.3e (drift %.2e)  angular momentum %.6e (drift %.2e)  center of mass drift %.2e\n",
+		d.Energy, drift.Energy, d.Momentum.X, d.Momentum.Y, d.Momentum.Z, drift.Momentum,
+		d.AngularMomentum.Len(), drift.AngularMomentum, drift.CenterOfMass,
+	)
+}
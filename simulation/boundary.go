@@ -0,0 +1,181 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+const DEFAULT_BOUNDARY = "open"
+
+/*
+Keeps the objects of the universe inside its box,
+from the origin to Size (z is ignored on 2D universes).
+Applied after every step, before the collisions.
+*/
+type BoundaryCondition interface {
+	Apply(u *Universe)
+}
+
+var BoundaryConditions = map[string]BoundaryCondition{
+	"open":     OpenBoundary{},
+	"reflect":  ReflectBoundary{},
+	"periodic": PeriodicBoundary{},
+	"absorb":   AbsorbBoundary{},
+}
+
+var BoundaryNames = []string{"open", "reflect", "periodic", "absorb"}
+
+func GetBoundaryCondition(name string) (BoundaryCondition, error) {
+	if name == "" {
+		name = DEFAULT_BOUNDARY
+	}
+	bc, ok := BoundaryConditions[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid boundary condition '%s'", name)
+	}
+	return bc, nil
+}
+
+// Objects fly away freely
+type OpenBoundary struct{}
+
+func (OpenBoundary) Apply(u *Universe) {}
+
+// The walls of the box reflect the objects that cross them
+type ReflectBoundary struct{}
+
+func (ReflectBoundary) Apply(u *Universe) {
+	size := u.GetBoxSize()
+	for _, obj := range u.Objects {
+		obj.Pos.X, obj.Vel.X = reflect(obj.Pos.X, obj.Vel.X, size.X)
+		obj.Pos.Y, obj.Vel.Y = reflect(obj.Pos.Y, obj.Vel.Y, size.Y)
+		obj.Pos.Z, obj.Vel.Z = reflect(obj.Pos.Z, obj.Vel.Z, size.Z)
+	}
+}
+
+func reflect(x, v, size float64) (float64, float64) {
+	if size <= 0 {
+		return x, v
+	}
+	if x < 0 {
+		x, v = -x, math.Abs(v)
+	}
+	if x > size {
+		x, v = 2*size-x, -math.Abs(v)
+	}
+	// Too fast to be reflected once
+	return math.Max(0, math.Min(x, size)), v
+}
+
+/*
+Objects leaving the box come back from the opposite side.
+The gravity and the collisions use the nearest image of each object.
+*/
+type PeriodicBoundary struct{}
+
+func (PeriodicBoundary) Apply(u *Universe) {
+	size := u.GetBoxSize()
+	for _, obj := range u.Objects {
+		obj.Pos.X = wrap(obj.Pos.X, size.X)
+		obj.Pos.Y = wrap(obj.Pos.Y, size.Y)
+		obj.Pos.Z = wrap(obj.Pos.Z, size.Z)
+	}
+}
+
+func wrap(x, size float64) float64 {
+	if size <= 0 {
+		return x
+	}
+	x = math.Mod(x, size)
+	if x < 0 {
+		x += size
+	}
+	return x
+}
+
+// Objects leaving the box are removed from the universe
+type AbsorbBoundary struct{}
+
+func (AbsorbBoundary) Apply(u *Universe) {
+	size := u.GetBoxSize()
+	outside := func(x, size float64) bool {
+		return size > 0 && (x < 0 || x > size)
+	}
+	u.RemoveObjects(func(obj *Object) bool {
+		if !outside(obj.Pos.X, size.X) && !outside(obj.Pos.Y, size.Y) && !outside(obj.Pos.Z, size.Z) {
+			return false
+		}
+		if !u.quiet {
+			log.Printf("[BOUNDARY] t=%v absorbed: %s (%v)\n", u.Time, obj.Name, obj.Mass)
+			u.Absorbed++
+		}
+		return true
+	})
+}
+
+func (u *Universe) SetBoundary(name string) error {
+	bc, err := GetBoundaryCondition(name)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = DEFAULT_BOUNDARY
+	}
+	u.BoundaryName = name
+	u.Boundary = bc
+	return nil
+}
+
+func (u *Universe) HandleBoundary() {
+	if u.Boundary == nil {
+		if err := u.SetBoundary(u.BoundaryName); err != nil {
+			u.SetBoundary(DEFAULT_BOUNDARY)
+		}
+	}
+	u.Boundary.Apply(u)
+}
+
+// Size of the box, without depth on 2D universes
+func (u *Universe) GetBoxSize() Coordinates3D {
+	size := u.Size
+	if u.Dimensions != 3 {
+		size.Z = 0
+	}
+	return size
+}
+
+/*
+Size of the periodic box, zero when the boundary isn't periodic.
+Used by the force solvers to find the nearest image of the objects.
+*/
+func (u *Universe) GetPeriod() Coordinates3D {
+	if u.BoundaryName != "periodic" {
+		return Coordinates3D{}
+	}
+	return u.GetBoxSize()
+}
+
+/*
+Returns the shortest vector between the images of two points.
+Axes with a zero period don't wrap.
+*/
+func MinimumImage(d, period Coordinates3D) Coordinates3D {
+	image := func(x, p float64) float64 {
+		if p <= 0 {
+			return x
+		}
+		return x - p*math.Round(x/p)
+	}
+	return Coordinates3D{image(d.X, period.X), image(d.Y, period.Y), image(d.Z, period.Z)}
+}
+
+// Vector from obj to the nearest image of tar
+func (u *Universe) GetDisplacement(obj, tar *Object) Coordinates3D {
+	return MinimumImage(tar.Pos.Sub(obj.Pos), u.GetPeriod())
+}
+
+// Distance between obj and the nearest image of tar
+func (u *Universe) GetDistance(obj, tar *Object) float64 {
+	return u.GetDisplacement(obj, tar).Len()
+}
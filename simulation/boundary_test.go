@@ -0,0 +1,53 @@
+package simulation
+
+import "testing"
+
+func TestBoundaryConditions(t *testing.T) {
+	tests := []struct {
+		boundary string
+		objects  int
+		pos      Coordinates3D
+		vel      Coordinates3D
+	}{
+		{"open", 1, Coordinates3D{X: 105, Y: 50, Z: 50}, Coordinates3D{X: 10}},
+		{"reflect", 1, Coordinates3D{X: 95, Y: 50, Z: 50}, Coordinates3D{X: -10}},
+		{"periodic", 1, Coordinates3D{X: 5, Y: 50, Z: 50}, Coordinates3D{X: 10}},
+		{"absorb", 0, Coordinates3D{}, Coordinates3D{}},
+	}
+	for _, tt := range tests {
+		obj := newTestObject(Coordinates3D{X: 95, Y: 50, Z: 50}, Coordinates3D{X: 10}, 1, 0.1)
+		u := newTestUniverse(t, func(u *Universe) {
+			u.Dimensions = 3
+			u.BoundaryName = tt.boundary
+		}, obj)
+		u.Step()
+
+		if len(u.Objects) != tt.objects {
+			t.Fatalf("%s: %d objects left, want %d", tt.boundary, len(u.Objects), tt.objects)
+		}
+		if tt.objects == 0 {
+			if u.Absorbed != 1 {
+				t.Errorf("%s: %d objects absorbed, want 1", tt.boundary, u.Absorbed)
+			}
+			continue
+		}
+		if obj.Pos.Sub(tt.pos).Len() > 1e-12 || obj.Vel != tt.vel {
+			t.Errorf("%s: object at %v moving at %v, want %v at %v", tt.boundary, obj.Pos, obj.Vel, tt.pos, tt.vel)
+		}
+	}
+}
+
+func TestPeriodicNearestImage(t *testing.T) {
+	// Across the border the objects are 10 apart instead of 90
+	u := newTestUniverse(t, func(u *Universe) {
+		u.BoundaryName = "periodic"
+	},
+		newTestObject(Coordinates3D{X: 5, Y: 50}, Coordinates3D{}, 1, 0.1),
+		newTestObject(Coordinates3D{X: 95, Y: 50}, Coordinates3D{}, 1, 0.1),
+	)
+	u.ApplyGravity()
+	want := Coordinates3D{X: -1.0 / 100}
+	if a := u.Objects[0].Accel; a.Sub(want).Len() > 1e-12 {
+		t.Errorf("acceleration of the object at x=5 is %v, want the pull of the image at x=-5, %v", a, want)
+	}
+}
@@ -0,0 +1,187 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+)
+
+const (
+	DEFAULT_COLLISION   = "merge"
+	DEFAULT_RESTITUTION = 0.5
+)
+
+/*
+Resolves the collision of two overlapping objects.
+Returns the object that must be removed from the universe, if any.
+*/
+type CollisionPolicy interface {
+	Collide(u *Universe, a, b *Object) *Object
+}
+
+var CollisionPolicies = map[string]CollisionPolicy{
+	"none":      NoCollision{},
+	"merge":     Merge{},
+	"elastic":   Elastic{},
+	"inelastic": Inelastic{},
+}
+
+var CollisionNames = []string{"none", "merge", "elastic", "inelastic"}
+
+func GetCollisionPolicy(name string) (CollisionPolicy, error) {
+	if name == "" {
+		name = DEFAULT_COLLISION
+	}
+	cp, ok := CollisionPolicies[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid collision policy '%s'", name)
+	}
+	return cp, nil
+}
+
+// Objects pass through each other
+type NoCollision struct{}
+
+func (NoCollision) Collide(u *Universe, a, b *Object) *Object {
+	return nil
+}
+
+/*
+Perfectly inelastic collision, the lighter object is absorbed
+by the heavier one conserving mass and momentum.
+The radius is recalculated from the combined density,
+by area on 2D universes and by volume on 3D ones.
+*/
+type Merge struct{}
+
+func (Merge) Collide(u *Universe, a, b *Object) *Object {
+	if b.Mass > a.Mass {
+		a, b = b, a
+	}
+
+	density, radius := CalcDensity, CalcRadius
+	if u.Dimensions == 3 {
+		density, radius = CalcVolumeDensity, CalcVolumeRadius
+	}
+
+	m := a.Mass + b.Mass
+	var d float64
+	if da, db := density(a.Mass, a.Radius), density(b.Mass, b.Radius); da > 0 && db > 0 {
+		d = m / (a.Mass/da + b.Mass/db)
+	}
+
+	a.Pos = a.Pos.Add(u.GetDisplacement(a, b).Scale(b.Mass / m))
+	a.Vel = a.GetMomentum().Add(b.GetMomentum()).Scale(1 / m)
+	a.Accel = a.Accel.Scale(a.Mass).Add(b.Accel.Scale(b.Mass)).Scale(1 / m)
+	a.Mass = m
+	if d > 0 && !math.IsInf(d, 0) {
+		a.Radius = radius(m, d)
+	} else {
+		a.Radius = math.Sqrt(a.Radius*a.Radius + b.Radius*b.Radius)
+	}
+	return b
+}
+
+// Objects bounce conserving the kinetic energy
+type Elastic struct{}
+
+func (Elastic) Collide(u *Universe, a, b *Object) *Object {
+	Bounce(a, b, u.GetDisplacement(a, b), 1)
+	return nil
+}
+
+/*
+Objects bounce losing energy, using the universe coefficient
+of restitution, clamped to [0, 1] on Init
+*/
+type Inelastic struct{}
+
+func (Inelastic) Collide(u *Universe, a, b *Object) *Object {
+	Bounce(a, b, u.GetDisplacement(a, b), u.Restitution)
+	return nil
+}
+
+/*
+Applies the impulse of a collision along the line between
+the centers and separates the objects.
+d is the vector from a to b, e is the coefficient of restitution, 1 is elastic.
+j = -(1+e)*vn / (1/m1 + 1/m2)
+*/
+func Bounce(a, b *Object, d Coordinates3D, e float64) {
+	r := d.Len()
+	n := Coordinates3D{X: 1}
+	if r > 0 {
+		n = d.Scale(1 / r)
+	}
+
+	vn := b.Vel.Sub(a.Vel).Dot(n)
+	if vn < 0 {
+		j := -(1 + e) * vn / (1/a.Mass + 1/b.Mass)
+		a.Vel = a.Vel.Sub(n.Scale(j / a.Mass))
+		b.Vel = b.Vel.Add(n.Scale(j / b.Mass))
+	}
+
+	// The lighter object is pushed further
+	overlap := a.Radius + b.Radius - r
+	if overlap > 0 {
+		m := a.Mass + b.Mass
+		a.Pos = a.Pos.Sub(n.Scale(overlap * b.Mass / m))
+		b.Pos = b.Pos.Add(n.Scale(overlap * a.Mass / m))
+	}
+}
+
+/*
+Finds the overlapping objects and resolves their collisions
+with the selected policy, removing the absorbed objects.
+Uses sweep and prune over the x axis, so on periodic universes
+the objects touching across the x border don't collide.
+*/
+func (u *Universe) HandleCollisions() {
+	if u.Collision == nil {
+		if err := u.SetCollision(u.CollisionName); err != nil {
+			u.SetCollision(DEFAULT_COLLISION)
+		}
+	}
+	if _, ok := u.Collision.(NoCollision); ok {
+		return
+	}
+
+	objs := make([]*Object, len(u.Objects))
+	copy(objs, u.Objects)
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].Pos.X-objs[i].Radius < objs[j].Pos.X-objs[j].Radius
+	})
+
+	removed := map[*Object]bool{}
+	for i, a := range objs {
+		if removed[a] {
+			continue
+		}
+		for _, b := range objs[i+1:] {
+			if b.Pos.X-b.Radius > a.Pos.X+a.Radius {
+				break
+			}
+			if removed[b] || u.GetDistance(a, b) > a.Radius+b.Radius {
+				continue
+			}
+
+			if !u.quiet {
+				log.Printf("[COLLISION] t=%v %s: %s (%v) x %s (%v)\n", u.Time, u.CollisionName, a.Name, a.Mass, b.Name, b.Mass)
+				u.Collisions++
+			}
+			if rm := u.Collision.Collide(u, a, b); rm != nil {
+				removed[rm] = true
+				if rm == a {
+					break
+				}
+			}
+		}
+	}
+
+	if len(removed) != 0 {
+		u.RemoveObjects(func(obj *Object) bool {
+			return removed[obj]
+		})
+	}
+}
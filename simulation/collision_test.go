@@ -0,0 +1,95 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCollisionConservation(t *testing.T) {
+	tests := []struct {
+		policy  string
+		objects int  // Objects left after the collision
+		kinetic bool // Whether the kinetic energy is conserved
+	}{
+		{"merge", 1, false},
+		{"elastic", 2, true},
+		{"inelastic", 2, false},
+	}
+	for _, tt := range tests {
+		u := newTestUniverse(t, func(u *Universe) {
+			u.Dimensions = 3
+			u.CollisionName = tt.policy
+			u.Restitution = 0.5
+		},
+			newTestObject(Coordinates3D{50, 50, 50}, Coordinates3D{1, 0.2, 0.1}, 2, 1),
+			newTestObject(Coordinates3D{51.5, 50.5, 50.3}, Coordinates3D{-1, 0, 0}, 3, 1),
+		)
+		mass, momentum, kinetic := getTestTotals(u)
+		u.HandleCollisions()
+		gotMass, gotMomentum, gotKinetic := getTestTotals(u)
+
+		if len(u.Objects) != tt.objects {
+			t.Errorf("%s: %d objects left, want %d", tt.policy, len(u.Objects), tt.objects)
+		}
+		if u.Collisions != 1 {
+			t.Errorf("%s: %d collisions counted, want 1", tt.policy, u.Collisions)
+		}
+		if math.Abs(gotMass-mass) > 1e-12 {
+			t.Errorf("%s: mass is %v, want %v", tt.policy, gotMass, mass)
+		}
+		if gotMomentum.Sub(momentum).Len() > 1e-12 {
+			t.Errorf("%s: momentum is %v, want %v", tt.policy, gotMomentum, momentum)
+		}
+		switch {
+		case tt.kinetic && math.Abs(gotKinetic-kinetic) > 1e-12:
+			t.Errorf("%s: kinetic energy is %v, want %v", tt.policy, gotKinetic, kinetic)
+		case !tt.kinetic && gotKinetic >= kinetic:
+			t.Errorf("%s: kinetic energy went from %v to %v, want it to drop", tt.policy, kinetic, gotKinetic)
+		}
+	}
+}
+
+func TestRestitution(t *testing.T) {
+	for _, tt := range []struct{ set, want float64 }{
+		{0, DEFAULT_RESTITUTION},
+		{0.3, 0.3},
+		{2, 1},
+		{-1, 0},
+	} {
+		u := newTestUniverse(t, func(u *Universe) {
+			u.Restitution = tt.set
+		})
+		if u.Restitution != tt.want {
+			t.Errorf("restitution %v: got %v, want %v", tt.set, u.Restitution, tt.want)
+		}
+	}
+}
+
+func TestQuietCollisions(t *testing.T) {
+	u := newTestUniverse(t, func(u *Universe) {
+		u.CollisionName = "elastic"
+	},
+		newTestObject(Coordinates3D{50, 50, 0}, Coordinates3D{1, 0, 0}, 1, 1),
+		newTestObject(Coordinates3D{51, 50, 0}, Coordinates3D{-1, 0, 0}, 1, 1),
+	)
+	c := u.Clone()
+	c.HandleCollisions()
+	if c.Collisions != 0 {
+		t.Errorf("clone counted %d collisions, want 0", c.Collisions)
+	}
+	if c.Objects[0].Vel == u.Objects[0].Vel {
+		t.Errorf("clone objects didn't bounce")
+	}
+}
+
+// Total mass, momentum and kinetic energy of the universe
+func getTestTotals(u *Universe) (float64, Coordinates3D, float64) {
+	var mass, kinetic float64
+	var momentum Coordinates3D
+	for _, obj := range u.Objects {
+		mass += obj.Mass
+		momentum = momentum.Add(obj.GetMomentum())
+		kinetic += 0.5 * obj.Mass * obj.Vel.Dot(obj.Vel)
+	}
+	return mass, momentum, kinetic
+}
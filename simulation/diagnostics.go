@@ -0,0 +1,171 @@
+package simulation
+
+import "math"
+
+/*
+Conserved quantities of the universe.
+The angular momentum is taken around the origin,
+on 2D universes only its z component is set.
+*/
+type Diagnostics struct {
+	Time            float64       `json:"time"`
+	Kinetic         float64       `json:"kinetic_energy"`
+	Potential       float64       `json:"potential_energy"`
+	Energy          float64       `json:"energy"`
+	Momentum        Coordinates3D `json:"momentum"`
+	AngularMomentum Coordinates3D `json:"angular_momentum"`
+	CenterOfMass    Coordinates3D `json:"center_of_mass"`
+	CenterOfMassVel Coordinates3D `json:"center_of_mass_velocity"`
+
+	// Scales used to make the momentum drifts relative
+	// since their totals are usually close to zero
+	MomentumScale        float64 `json:"-"` // Σ m*|v|, at least M*vu
+	AngularMomentumScale float64 `json:"-"` // Σ m*|r x v|, at least Σ m*|r|*vu
+}
+
+/*
+Relative drift of the conserved quantities since the reference.
+Energy is relative to |E0|, momentum to Σ m*|v| and angular
+momentum to Σ m*|r x v|, all taken at the reference.
+Systems starting at rest use the speed vu = √(|U|/M) instead,
+the drifts are absolute when every scale is 0.
+CenterOfMass is the distance between the center of mass and
+where it should be moving at the initial velocity.
+*/
+type Drift struct {
+	Energy          float64 `json:"energy"`
+	Momentum        float64 `json:"momentum"`
+	AngularMomentum float64 `json:"angular_momentum"`
+	CenterOfMass    float64 `json:"center_of_mass"`
+}
+
+func (u *Universe) GetKineticEnergy() float64 {
+	var k float64
+	for _, obj := range u.Objects {
+		k += 0.5 * obj.Mass * obj.Vel.Dot(obj.Vel)
+	}
+	return k
+}
+
+/*
+Sums the potential energy of every pair of objects, using the
+universe softening. Takes O(N^2), in parallel when the universe
+parallel mode is on.
+*/
+func (u *Universe) GetPotentialEnergy() float64 {
+	objs := u.Objects
+	pot := make([]float64, len(objs))
+	ParallelFor(len(objs), u.getWorkers(), func(i int) {
+		obj := objs[i]
+		for _, tar := range objs[i+1:] {
+			if r := u.GetDistance(obj, tar); r > 0 || u.Softening.Length > 0 {
+				pot[i] += u.Softening.CalcPotentialEnergy(obj.Mass, tar.Mass, r, u.Gconst)
+			}
+		}
+	})
+
+	var p float64
+	for _, v := range pot {
+		p += v
+	}
+	return p
+}
+
+func (u *Universe) GetMomentum() Coordinates3D {
+	var p Coordinates3D
+	for _, obj := range u.Objects {
+		p = p.Add(obj.GetMomentum())
+	}
+	return p
+}
+
+// L = Σ m*(r x v)
+func (u *Universe) GetAngularMomentum() Coordinates3D {
+	var l Coordinates3D
+	for _, obj := range u.Objects {
+		l = l.Add(obj.Pos.Cross(obj.Vel).Scale(obj.Mass))
+	}
+	return l
+}
+
+// Returns the position and velocity of the center of mass
+func (u *Universe) GetCenterOfMass() (Coordinates3D, Coordinates3D) {
+	var m float64
+	var p, v Coordinates3D
+	for _, obj := range u.Objects {
+		m += obj.Mass
+		p = p.Add(obj.Pos.Scale(obj.Mass))
+		v = v.Add(obj.Vel.Scale(obj.Mass))
+	}
+	if m == 0 {
+		return p, v
+	}
+	return p.Scale(1 / m), v.Scale(1 / m)
+}
+
+func (u *Universe) GetDiagnostics() Diagnostics {
+	d := Diagnostics{
+		Time:            u.Time,
+		Kinetic:         u.GetKineticEnergy(),
+		Potential:       u.GetPotentialEnergy(),
+		Momentum:        u.GetMomentum(),
+		AngularMomentum: u.GetAngularMomentum(),
+	}
+	d.Energy = d.Kinetic + d.Potential
+	d.CenterOfMass, d.CenterOfMassVel = u.GetCenterOfMass()
+
+	var m, mr float64
+	for _, obj := range u.Objects {
+		m += obj.Mass
+		mr += obj.Mass * obj.Pos.Len()
+		d.MomentumScale += obj.Mass * obj.Vel.Len()
+		d.AngularMomentumScale += obj.Mass * obj.Pos.Cross(obj.Vel).Len()
+	}
+
+	// Speed gained by falling through the potential
+	if m > 0 {
+		vu := math.Sqrt(math.Abs(d.Potential) / m)
+		d.MomentumScale = math.Max(d.MomentumScale, m*vu)
+		d.AngularMomentumScale = math.Max(d.AngularMomentumScale, mr*vu)
+	}
+	return d
+}
+
+/*
+Returns the current diagnostics and their drift from the reference.
+The reference is taken on the first call after ResetDiagnostics.
+*/
+func (u *Universe) GetDrift() (Diagnostics, Drift) {
+	d := u.GetDiagnostics()
+	if u.Reference == nil {
+		ref := d
+		u.Reference = &ref
+	}
+	return d, u.Reference.GetDrift(d)
+}
+
+/*
+Forgets the reference of the drift, must be called when the
+conserved quantities are changed from outside of the simulation
+*/
+func (u *Universe) ResetDiagnostics() {
+	u.Reference = nil
+}
+
+// Drift of d relative to the reference ref
+func (ref Diagnostics) GetDrift(d Diagnostics) Drift {
+	rel := func(v, scale float64) float64 {
+		if scale == 0 {
+			return v
+		}
+		return v / scale
+	}
+
+	com := ref.CenterOfMass.Add(ref.CenterOfMassVel.Scale(d.Time - ref.Time))
+	return Drift{
+		Energy:          rel(math.Abs(d.Energy-ref.Energy), math.Abs(ref.Energy)),
+		Momentum:        rel(d.Momentum.Sub(ref.Momentum).Len(), ref.MomentumScale),
+		AngularMomentum: rel(d.AngularMomentum.Sub(ref.AngularMomentum).Len(), ref.AngularMomentumScale),
+		CenterOfMass:    d.CenterOfMass.Sub(com).Len(),
+	}
+}
@@ -0,0 +1,58 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetDiagnostics(t *testing.T) {
+	u := newTestUniverse(t, nil,
+		newTestObject(Coordinates3D{}, Coordinates3D{Y: 1}, 2, 0.1),
+		newTestObject(Coordinates3D{X: 3}, Coordinates3D{Y: -2}, 1, 0.1),
+	)
+	d := u.GetDiagnostics()
+	want := Diagnostics{
+		Kinetic:         3,
+		Potential:       -2.0 / 3,
+		Energy:          3 - 2.0/3,
+		AngularMomentum: Coordinates3D{Z: -6},
+		CenterOfMass:    Coordinates3D{X: 1},
+	}
+	if math.Abs(d.Kinetic-want.Kinetic) > 1e-12 || math.Abs(d.Potential-want.Potential) > 1e-12 ||
+		math.Abs(d.Energy-want.Energy) > 1e-12 || d.AngularMomentum.Sub(want.AngularMomentum).Len() > 1e-12 {
+		t.Errorf("energies and angular momentum are %+v, want %+v", d, want)
+	}
+	if d.Momentum.Len() > 1e-12 || d.CenterOfMassVel.Len() > 1e-12 || d.CenterOfMass.Sub(want.CenterOfMass).Len() > 1e-12 {
+		t.Errorf("momentum and center of mass are %+v, want %+v", d, want)
+	}
+}
+
+func TestGetDrift(t *testing.T) {
+	u := newTestUniverse(t, func(u *Universe) {
+		u.IntegratorName = "leapfrog"
+	}, newTestBinary(10, 1000)...)
+
+	if _, drift := u.GetDrift(); drift != (Drift{}) {
+		t.Fatalf("drift is %+v on the reference, want zero", drift)
+	}
+	for i := 0; i < 500; i++ {
+		u.Step()
+	}
+	_, drift := u.GetDrift()
+	if drift.Energy > 1e-8 || drift.Momentum > 1e-12 || drift.AngularMomentum > 1e-8 || drift.CenterOfMass > 1e-9 {
+		t.Errorf("drift is %+v after half a period, want about zero", drift)
+	}
+
+	// Giving the universe a kick moves its center of mass away from the expected one
+	for _, obj := range u.Objects {
+		obj.Vel.X += 1
+	}
+	u.Step()
+	if _, drift := u.GetDrift(); drift.Momentum == 0 || drift.Energy == 0 || drift.CenterOfMass == 0 {
+		t.Errorf("drift is %+v after a kick, want it to grow", drift)
+	}
+	u.ResetDiagnostics()
+	if _, drift := u.GetDrift(); drift != (Drift{}) {
+		t.Errorf("drift is %+v after a reset, want zero", drift)
+	}
+}
@@ -0,0 +1,83 @@
+package simulation
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+/*
+In place radix-2 FFT, len(x) must be a power of 2.
+The inverse transform isn't normalized.
+*/
+func FFT(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+
+	shift := 64 - uint(bits.Len(uint(n-1)))
+	for i := range x {
+		if j := int(bits.Reverse64(uint64(i)) >> shift); j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		sin, cos := math.Sincos(sign * 2 * math.Pi / float64(size))
+		step := complex(cos, sin)
+		half := size / 2
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < half; k++ {
+				a, b := x[start+k], x[start+k+half]*w
+				x[start+k], x[start+k+half] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+// Line buffers of FFTGrid, shared between its calls and workers
+var fftLinePool = sync.Pool{New: func() any {
+	return new([]complex128)
+}}
+
+/*
+FFT of a grid of shape[0] x shape[1] x shape[2] cells stored
+with the first axis varying the fastest. Every axis length
+must be a power of 2, axes of length 1 are skipped.
+*/
+func FFTGrid(grid []complex128, shape [3]int, inverse bool, workers int) {
+	stride := [3]int{1, shape[0], shape[0] * shape[1]}
+	for a := 0; a < 3; a++ {
+		n := shape[a]
+		if n <= 1 {
+			continue
+		}
+		lines := len(grid) / n
+		ParallelFor(lines, workers, func(l int) {
+			// First cell of the line l along the axis a
+			low := l % stride[a]
+			start := low + (l-low)*n
+
+			buf := fftLinePool.Get().(*[]complex128)
+			if cap(*buf) < n {
+				*buf = make([]complex128, n)
+			}
+			line := (*buf)[:n]
+			for i := range line {
+				line[i] = grid[start+i*stride[a]]
+			}
+			FFT(line, inverse)
+			for i, v := range line {
+				grid[start+i*stride[a]] = v
+			}
+			fftLinePool.Put(buf)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package simulation
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Size of the universes used on the tests
+const TEST_UNIVERSE_SIZE = 100
+
+/*
+Returns a universe with G = 1 holding the given objects.
+setup, when not nil, changes the universe before Init.
+*/
+func newTestUniverse(tb testing.TB, setup func(u *Universe), objs ...*Object) *Universe {
+	tb.Helper()
+	u := NewUniverse(Coordinates3D{X: TEST_UNIVERSE_SIZE, Y: TEST_UNIVERSE_SIZE, Z: TEST_UNIVERSE_SIZE}, 1, objs...)
+	if setup != nil {
+		setup(u)
+	}
+	if err := u.Init(); err != nil {
+		tb.Fatal(err)
+	}
+	return u
+}
+
+func newTestObject(pos, vel Coordinates3D, mass, radius float64) *Object {
+	obj := NewObject("", color.RGBA{}, pos, mass, radius)
+	obj.Vel = vel
+	return obj
+}
+
+/*
+Returns two equal masses on a circular orbit sep apart around
+the center of the universe, that make a full period in the given time
+*/
+func newTestBinary(sep, period float64) []*Object {
+	// v = 2π(sep/2)/period and v² = m/(2 sep) with G = 1
+	v := math.Pi * sep / period
+	m := 2 * sep * v * v
+	center := Coordinates3D{X: TEST_UNIVERSE_SIZE / 2, Y: TEST_UNIVERSE_SIZE / 2, Z: TEST_UNIVERSE_SIZE / 2}
+	return []*Object{
+		newTestObject(center.Add(Coordinates3D{X: -sep / 2}), Coordinates3D{Y: -v}, m, 0.1),
+		newTestObject(center.Add(Coordinates3D{X: sep / 2}), Coordinates3D{Y: v}, m, 0.1),
+	}
+}
+
+// Returns qtt objects spread over the middle of the universe with random velocities
+func newTestCluster(qtt int, seed int64) []*Object {
+	rng := rand.New(rand.NewSource(seed))
+	objs := make([]*Object, qtt)
+	for i := range objs {
+		pos := Coordinates3D{
+			X: TEST_UNIVERSE_SIZE * (0.25 + 0.5*rng.Float64()),
+			Y: TEST_UNIVERSE_SIZE * (0.25 + 0.5*rng.Float64()),
+			Z: TEST_UNIVERSE_SIZE * (0.25 + 0.5*rng.Float64()),
+		}
+		vel := Coordinates3D{X: rng.Float64() - 0.5, Y: rng.Float64() - 0.5, Z: rng.Float64() - 0.5}
+		objs[i] = newTestObject(pos, vel, 1+rng.Float64(), 0.1)
+	}
+	return objs
+}
@@ -0,0 +1,136 @@
+package simulation
+
+import "fmt"
+
+const DEFAULT_INTEGRATOR = "euler"
+
+/*
+Advances the objects of a universe by one time step.
+Accelerations are evaluated through Universe.ApplyGravity.
+*/
+type Integrator interface {
+	Step(u *Universe, dt float64)
+}
+
+var Integrators = map[string]Integrator{
+	"euler":    Euler{},
+	"leapfrog": Leapfrog{},
+	"verlet":   VelocityVerlet{},
+	"rk4":      RK4{},
+}
+
+var IntegratorNames = []string{"euler", "leapfrog", "verlet", "rk4"}
+
+func GetIntegrator(name string) (Integrator, error) {
+	if name == "" {
+		name = DEFAULT_INTEGRATOR
+	}
+	in, ok := Integrators[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid integrator '%s'", name)
+	}
+	return in, nil
+}
+
+/*
+Semi-implicit (symplectic) Euler.
+v += a*dt
+x += v*dt
+*/
+type Euler struct{}
+
+func (Euler) Step(u *Universe, dt float64) {
+	u.ApplyGravity()
+	for _, obj := range u.Objects {
+		obj.Vel = obj.Vel.Add(obj.Accel.Scale(dt))
+		obj.Pos = obj.Pos.Add(obj.Vel.Scale(dt))
+	}
+}
+
+/*
+Drift-kick-drift leapfrog.
+x += v*dt/2
+v += a*dt
+x += v*dt/2
+*/
+type Leapfrog struct{}
+
+func (Leapfrog) Step(u *Universe, dt float64) {
+	for _, obj := range u.Objects {
+		obj.Pos = obj.Pos.Add(obj.Vel.Scale(dt / 2))
+	}
+	u.ApplyGravity()
+	for _, obj := range u.Objects {
+		obj.Vel = obj.Vel.Add(obj.Accel.Scale(dt))
+		obj.Pos = obj.Pos.Add(obj.Vel.Scale(dt / 2))
+	}
+}
+
+/*
+Kick-drift-kick velocity Verlet.
+v += a(x)*dt/2
+x += v*dt
+v += a(x)*dt/2
+*/
+type VelocityVerlet struct{}
+
+func (VelocityVerlet) Step(u *Universe, dt float64) {
+	// The accelerations are recomputed at the start since objects,
+	// masses or constants may have been changed between steps
+	u.ApplyGravity()
+	for _, obj := range u.Objects {
+		obj.Vel = obj.Vel.Add(obj.Accel.Scale(dt / 2))
+		obj.Pos = obj.Pos.Add(obj.Vel.Scale(dt))
+	}
+	u.ApplyGravity()
+	for _, obj := range u.Objects {
+		obj.Vel = obj.Vel.Add(obj.Accel.Scale(dt / 2))
+	}
+}
+
+/*
+Classic fourth order Runge-Kutta.
+x' = v
+v' = a(x)
+*/
+type RK4 struct{}
+
+func (RK4) Step(u *Universe, dt float64) {
+	n := len(u.Objects)
+	pos0 := make([]Coordinates3D, n)
+	vel0 := make([]Coordinates3D, n)
+	for i, obj := range u.Objects {
+		pos0[i], vel0[i] = obj.Pos, obj.Vel
+	}
+
+	// k[s][i] holds the derivatives of the object i on the stage s
+	var kx, kv [4][]Coordinates3D
+	for s := range kx {
+		kx[s] = make([]Coordinates3D, n)
+		kv[s] = make([]Coordinates3D, n)
+	}
+
+	stageDt := [4]float64{0, dt / 2, dt / 2, dt}
+	for s := 0; s < 4; s++ {
+		for i, obj := range u.Objects {
+			obj.Pos, obj.Vel = pos0[i], vel0[i]
+			if s > 0 {
+				obj.Pos = obj.Pos.Add(kx[s-1][i].Scale(stageDt[s]))
+				obj.Vel = obj.Vel.Add(kv[s-1][i].Scale(stageDt[s]))
+			}
+		}
+		u.ApplyGravity()
+		for i, obj := range u.Objects {
+			kx[s][i] = obj.Vel
+			kv[s][i] = obj.Accel
+		}
+	}
+
+	for i, obj := range u.Objects {
+		dx := kx[0][i].Add(kx[1][i].Scale(2)).Add(kx[2][i].Scale(2)).Add(kx[3][i])
+		dv := kv[0][i].Add(kv[1][i].Scale(2)).Add(kv[2][i].Scale(2)).Add(kv[3][i])
+		obj.Pos = pos0[i].Add(dx.Scale(dt / 6))
+		obj.Vel = vel0[i].Add(dv.Scale(dt / 6))
+		obj.Accel = kv[0][i]
+	}
+}
@@ -0,0 +1,41 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntegratorsCircularOrbit(t *testing.T) {
+	tests := []struct {
+		integrator string
+		energy     float64 // Highest relative energy drift
+		closure    float64 // Highest distance from the start after a period, relative to the separation
+	}{
+		{"euler", 4e-4, 1e-3},
+		{"leapfrog", 1e-8, 5e-4},
+		{"verlet", 1e-8, 5e-4},
+		{"rk4", 1e-10, 1e-8},
+	}
+	for _, tt := range tests {
+		// A period takes 1000 steps
+		u := newTestUniverse(t, func(u *Universe) {
+			u.IntegratorName = tt.integrator
+		}, newTestBinary(10, 1000)...)
+		start := u.Objects[1].Pos
+		ref := u.GetDiagnostics().Energy
+
+		var drift float64
+		for i := 0; i < 1000; i++ {
+			u.Step()
+			drift = math.Max(drift, math.Abs((u.GetDiagnostics().Energy-ref)/ref))
+		}
+		closure := u.Objects[1].Pos.Sub(start).Len() / 10
+
+		if drift > tt.energy {
+			t.Errorf("%s: energy drifts %g, want at most %g", tt.integrator, drift, tt.energy)
+		}
+		if closure > tt.closure {
+			t.Errorf("%s: ends %g separations away from the start, want at most %g", tt.integrator, closure, tt.closure)
+		}
+	}
+}
@@ -7,13 +7,61 @@ import (
 // Gravitational Constant
 var G = 6.674 * math.Pow(10, -11)
 
-type Coordinates2D struct {
+// Z is always 0 on 2D universes
+type Coordinates3D struct {
 	X float64 `json:"x,omitempty"`
 	Y float64 `json:"y,omitempty"`
+	Z float64 `json:"z,omitempty"`
+}
+
+func (c Coordinates3D) Add(o Coordinates3D) Coordinates3D {
+	return Coordinates3D{c.X + o.X, c.Y + o.Y, c.Z + o.Z}
+}
+
+func (c Coordinates3D) Sub(o Coordinates3D) Coordinates3D {
+	return Coordinates3D{c.X - o.X, c.Y - o.Y, c.Z - o.Z}
+}
+
+func (c Coordinates3D) Scale(k float64) Coordinates3D {
+	return Coordinates3D{c.X * k, c.Y * k, c.Z * k}
+}
+
+func (c Coordinates3D) Dot(o Coordinates3D) float64 {
+	return c.X*o.X + c.Y*o.Y + c.Z*o.Z
+}
+
+func (c Coordinates3D) Cross(o Coordinates3D) Coordinates3D {
+	return Coordinates3D{
+		c.Y*o.Z - c.Z*o.Y,
+		c.Z*o.X - c.X*o.Z,
+		c.X*o.Y - c.Y*o.X,
+	}
+}
+
+func (c Coordinates3D) Len() float64 {
+	return math.Sqrt(c.X*c.X + c.Y*c.Y + c.Z*c.Z)
+}
+
+// Rotates counterclockwise around the z axis by a radians
+func (c Coordinates3D) Rotate(a float64) Coordinates3D {
+	sin, cos := math.Sincos(a)
+	return Coordinates3D{c.X*cos - c.Y*sin, c.X*sin + c.Y*cos, c.Z}
+}
+
+// Rotates counterclockwise around the x axis by a radians
+func (c Coordinates3D) RotateX(a float64) Coordinates3D {
+	sin, cos := math.Sincos(a)
+	return Coordinates3D{c.X, c.Y*cos - c.Z*sin, c.Y*sin + c.Z*cos}
+}
+
+// Rotates counterclockwise around the y axis by a radians
+func (c Coordinates3D) RotateY(a float64) Coordinates3D {
+	sin, cos := math.Sincos(a)
+	return Coordinates3D{c.X*cos + c.Z*sin, c.Y, -c.X*sin + c.Z*cos}
 }
 
 type Vector2 struct {
-	Direction Coordinates2D
+	Direction Coordinates3D
 	Magnitude float64
 }
 
@@ -81,20 +129,35 @@ func CalcProportionalLeg(h1, h2, c1 float64) float64 {
 	return h2 * c1 / h1
 }
 
-func CalcResultingPosition(pos Coordinates2D, vel Vector2, accel float64) Coordinates2D {
+func CalcResultingPosition(pos, vel Coordinates3D) Coordinates3D {
 	// log.Println("Pos X:", pos.X)
 	// log.Println("Pos Y:", pos.Y)
-	// log.Println("Direction X:", vel.Direction.X)
-	// log.Println("Direction Y:", vel.Direction.Y)
-	// log.Println("Resulting Pos X:", pos.X+vel.Direction.X*vel.Magnitude)
-	// log.Println("Resulting Pos Y:", pos.Y+vel.Direction.Y*vel.Magnitude)
-	// log.Println("Velocity magnitude:", vel.Magnitude)
-	return Coordinates2D{
-		X: pos.X + vel.Direction.X*vel.Magnitude,
-		Y: pos.Y + vel.Direction.Y*vel.Magnitude,
-	}
+	// log.Println("Resulting Pos X:", pos.X+vel.X)
+	// log.Println("Resulting Pos Y:", pos.Y+vel.Y)
+	return pos.Add(vel)
 }
 
 func CalcDensity(m, r float64) float64 {
 	return m / (math.Pi * math.Pow(r, 2))
 }
+
+/*
+Inverse of CalcDensity.
+r = √(m/(π*d))
+*/
+func CalcRadius(m, d float64) float64 {
+	return math.Sqrt(m / (math.Pi * d))
+}
+
+// Density of a sphere, used on 3D universes
+func CalcVolumeDensity(m, r float64) float64 {
+	return m / (4.0 / 3 * math.Pi * r * r * r)
+}
+
+/*
+Inverse of CalcVolumeDensity.
+r = ∛(m/(4/3*π*d))
+*/
+func CalcVolumeRadius(m, d float64) float64 {
+	return math.Cbrt(m / (4.0 / 3 * math.Pi * d))
+}
@@ -0,0 +1,353 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	DEFAULT_MESH_GRID_SIZE  = 64
+	DEFAULT_MESH_ASSIGNMENT = "cic"
+	DEFAULT_MESH_BOUNDARY   = "isolated"
+
+	// Cells kept between the objects and the border of an isolated grid
+	MESH_MARGIN = 3
+)
+
+/*
+Options of the particle-mesh force solver.
+
+	grid_size : Cells on each axis, must be a power of 2.
+	assignment : ngp (nearest grid point), cic (cloud in cell) or tsc (triangular shaped cloud).
+	boundary : isolated or periodic.
+
+Both grids cover the universe box. The objects closer than MESH_MARGIN
+cells to the border of an isolated grid, or out of it, are left out of
+the grid and use the direct sum.
+*/
+type MeshOpt struct {
+	GridSize   int    `json:"grid_size,omitempty"`
+	Assignment string `json:"assignment,omitempty"`
+	Boundary   string `json:"boundary,omitempty"`
+}
+
+var MeshAssignments = []string{"ngp", "cic", "tsc"}
+
+var MeshBoundaries = []string{"isolated", "periodic"}
+
+func (opt *MeshOpt) Init() error {
+	if opt.GridSize == 0 {
+		opt.GridSize = DEFAULT_MESH_GRID_SIZE
+	}
+	if opt.Assignment == "" {
+		opt.Assignment = DEFAULT_MESH_ASSIGNMENT
+	}
+	if opt.Boundary == "" {
+		opt.Boundary = DEFAULT_MESH_BOUNDARY
+	}
+
+	if opt.GridSize < 2*MESH_MARGIN+2 || opt.GridSize&(opt.GridSize-1) != 0 {
+		return fmt.Errorf("invalid mesh grid size %d, must be a power of 2 not smaller than %d", opt.GridSize, 2*MESH_MARGIN+2)
+	}
+	if !contains(MeshAssignments, opt.Assignment) {
+		return fmt.Errorf("invalid mesh assignment '%s'", opt.Assignment)
+	}
+	if !contains(MeshBoundaries, opt.Boundary) {
+		return fmt.Errorf("invalid mesh boundary '%s'", opt.Boundary)
+	}
+	return nil
+}
+
+func getAxis(c Coordinates3D, a int) float64 {
+	switch a {
+	case 0:
+		return c.X
+	case 1:
+		return c.Y
+	}
+	return c.Z
+}
+
+/*
+Grid of the particle-mesh solver.
+The isolated grids are zero padded to twice their size on the FFT
+(Hockney & Eastwood), only the first N cells of each axis are used
+by the objects. 2D universes have a single cell on the z axis.
+*/
+type Mesh struct {
+	N        [3]int // Cells used by the objects on each axis
+	M        [3]int // Cells of the FFT grid on each axis
+	H        [3]float64
+	Origin   Coordinates3D
+	Periodic bool
+	Assign   string
+
+	// Mass of each cell, replaced by the potential on Solve
+	Grid []complex128
+}
+
+// Green's function on Fourier space, reused while the grid doesn't change
+type meshKernel struct {
+	key  meshKernelKey
+	data []complex128
+}
+
+type meshKernelKey struct {
+	M         [3]int
+	H         [3]float64
+	Gconst    float64
+	Softening Softening
+	Periodic  bool
+}
+
+func NewMesh(u *Universe) *Mesh {
+	opt := u.Mesh
+	n := opt.GridSize
+	periodic := opt.Boundary == "periodic" || u.BoundaryName == "periodic"
+	m := &Mesh{Periodic: periodic, Assign: opt.Assignment}
+
+	axes := u.Dimensions
+	if axes != 3 {
+		axes = 2
+	}
+	size := [3]float64{u.Size.X, u.Size.Y, u.Size.Z}
+	for a := 0; a < 3; a++ {
+		m.N[a], m.M[a], m.H[a] = 1, 1, 1
+		if a >= axes {
+			continue
+		}
+		if size[a] <= 0 {
+			size[a] = 1
+		}
+		m.N[a] = n
+		m.M[a] = n
+		if !m.Periodic {
+			m.M[a] = 2 * n
+		}
+		m.H[a] = size[a] / float64(n)
+	}
+	m.Grid = u.getMeshGrid(m.M[0] * m.M[1] * m.M[2])
+	return m
+}
+
+// Returns the cleared grid buffer of the universe, reused while its size doesn't change
+func (u *Universe) getMeshGrid(size int) []complex128 {
+	if len(u.meshGrid) != size {
+		u.meshGrid = make([]complex128, size)
+		return u.meshGrid
+	}
+	for i := range u.meshGrid {
+		u.meshGrid[i] = 0
+	}
+	return u.meshGrid
+}
+
+// Whether the object at pos is deposited on the grid
+func (m *Mesh) Contains(pos Coordinates3D) bool {
+	if m.Periodic {
+		return true
+	}
+	for a := 0; a < 3; a++ {
+		if m.N[a] == 1 {
+			continue
+		}
+		x := (getAxis(pos, a) - getAxis(m.Origin, a)) / m.H[a]
+		if x < MESH_MARGIN || x > float64(m.N[a]-MESH_MARGIN) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Mesh) index(i [3]int) int {
+	for a := 0; a < 3; a++ {
+		if m.Periodic {
+			i[a] = ((i[a] % m.N[a]) + m.N[a]) % m.N[a]
+		} else if i[a] < 0 {
+			i[a] = 0
+		} else if i[a] >= m.N[a] {
+			i[a] = m.N[a] - 1
+		}
+	}
+	return (i[2]*m.M[1]+i[1])*m.M[0] + i[0]
+}
+
+/*
+Returns the first cell touched by the object on the axis
+and the weight of each touched cell
+*/
+func (m *Mesh) getWeights(pos Coordinates3D, a int) (int, []float64) {
+	if m.N[a] == 1 {
+		return 0, []float64{1}
+	}
+	x := (getAxis(pos, a) - getAxis(m.Origin, a)) / m.H[a]
+	if m.Periodic {
+		n := float64(m.N[a])
+		x = math.Mod(math.Mod(x, n)+n, n)
+	}
+
+	// Cell i is centered at i+0.5
+	s := x - 0.5
+	switch m.Assign {
+	case "ngp":
+		return int(math.Floor(x)), []float64{1}
+	case "tsc":
+		i := math.Floor(s + 0.5)
+		d := s - i
+		return int(i) - 1, []float64{0.5 * (0.5 - d) * (0.5 - d), 0.75 - d*d, 0.5 * (0.5 + d) * (0.5 + d)}
+	}
+	i := math.Floor(s)
+	f := s - i
+	return int(i), []float64{1 - f, f}
+}
+
+// Calls f for every cell touched by the object with its weight
+func (m *Mesh) forEachCell(pos Coordinates3D, f func(c [3]int, w float64)) {
+	var start [3]int
+	var ws [3][]float64
+	for a := 0; a < 3; a++ {
+		start[a], ws[a] = m.getWeights(pos, a)
+	}
+	for k, wz := range ws[2] {
+		for j, wy := range ws[1] {
+			for i, wx := range ws[0] {
+				f([3]int{start[0] + i, start[1] + j, start[2] + k}, wx*wy*wz)
+			}
+		}
+	}
+}
+
+// Spreads the mass of the objects over the grid
+func (m *Mesh) Deposit(objs []*Object) {
+	for _, obj := range objs {
+		mass := obj.Mass
+		m.forEachCell(obj.Pos, func(c [3]int, w float64) {
+			m.Grid[m.index(c)] += complex(mass*w, 0)
+		})
+	}
+}
+
+/*
+Returns the Green's function of the grid on Fourier space.
+The grid convolves the mass with the softened potential of a
+point mass, 2D universes keep the 1/r potential of the other
+solvers on the plane.
+Periodic grids solve the periodic Poisson equation, -4πG/k²
+(-2πG/k on 2D universes) without the k = 0 mode, so the mean
+density doesn't pull. The softening is added on real space as
+the difference from the 1/r potential around each cell.
+*/
+func (m *Mesh) getKernel(u *Universe) []complex128 {
+	key := meshKernelKey{m.M, m.H, u.Gconst, u.Softening, m.Periodic}
+	if k := u.meshKernel; k != nil && k.key == key {
+		return k.data
+	}
+
+	// Without softening the cell of the object is taken at half a cell
+	soft := u.Softening
+	r0 := 0.5 * math.Min(m.H[0], m.H[1])
+	kernel := make([]complex128, len(m.Grid))
+	for idx := range kernel {
+		var r2 float64
+		for a, i := range m.cell(idx) {
+			d := float64(wrapIndex(i, m.M[a])) * m.H[a]
+			r2 += d * d
+		}
+		r := math.Sqrt(r2)
+		switch {
+		case m.Periodic && r == 0:
+			continue
+		case m.Periodic:
+			kernel[idx] = complex(-u.Gconst*(soft.PotentialKernel(r)-1/r), 0)
+		case r == 0 && soft.Length == 0:
+			kernel[idx] = complex(-u.Gconst*soft.PotentialKernel(r0), 0)
+		default:
+			kernel[idx] = complex(-u.Gconst*soft.PotentialKernel(r), 0)
+		}
+	}
+	FFTGrid(kernel, m.M, false, u.getWorkers())
+	if m.Periodic {
+		m.addPeriodicKernel(kernel, u.Gconst)
+	}
+
+	u.meshKernel = &meshKernel{key, kernel}
+	return kernel
+}
+
+// Adds the Fourier space Green's function of the periodic grid to the kernel
+func (m *Mesh) addPeriodicKernel(kernel []complex128, gConst float64) {
+	planar := m.N[2] == 1
+	volume := m.H[0] * m.H[1]
+	if !planar {
+		volume *= m.H[2]
+	}
+	for idx := range kernel {
+		var k2 float64
+		for a, i := range m.cell(idx) {
+			k := 2 * math.Pi * float64(wrapIndex(i, m.M[a])) / (float64(m.M[a]) * m.H[a])
+			k2 += k * k
+		}
+		if k2 == 0 {
+			continue
+		}
+		g := -4 * math.Pi * gConst / k2
+		if planar {
+			g = -2 * math.Pi * gConst / math.Sqrt(k2)
+		}
+		kernel[idx] += complex(g/volume, 0)
+	}
+}
+
+// Position of the cell on the FFT grid
+func (m *Mesh) cell(idx int) [3]int {
+	return [3]int{idx % m.M[0], idx / m.M[0] % m.M[1], idx / (m.M[0] * m.M[1])}
+}
+
+// Signed distance to the cell 0 on a periodic axis of n cells
+func wrapIndex(i, n int) int {
+	if i > n/2 {
+		return i - n
+	}
+	return i
+}
+
+// Replaces the mass of the grid by the gravitational potential
+func (m *Mesh) Solve(u *Universe) {
+	kernel := m.getKernel(u)
+	workers := u.getWorkers()
+	FFTGrid(m.Grid, m.M, false, workers)
+	for i, k := range kernel {
+		m.Grid[i] *= k
+	}
+	FFTGrid(m.Grid, m.M, true, workers)
+
+	norm := complex(1/float64(len(m.Grid)), 0)
+	for i := range m.Grid {
+		m.Grid[i] *= norm
+	}
+}
+
+func (m *Mesh) GetPotential(c [3]int) float64 {
+	return real(m.Grid[m.index(c)])
+}
+
+/*
+Interpolates the acceleration on the position with the same
+weights used to deposit the mass, the gradient of the potential
+is taken by central differences
+*/
+func (m *Mesh) GetAcceleration(pos Coordinates3D) Coordinates3D {
+	var accel [3]float64
+	m.forEachCell(pos, func(c [3]int, w float64) {
+		for a := 0; a < 3; a++ {
+			if m.N[a] == 1 {
+				continue
+			}
+			next, prev := c, c
+			next[a]++
+			prev[a]--
+			accel[a] -= w * (m.GetPotential(next) - m.GetPotential(prev)) / (2 * m.H[a])
+		}
+	})
+	return Coordinates3D{accel[0], accel[1], accel[2]}
+}
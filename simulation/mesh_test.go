@@ -0,0 +1,120 @@
+package simulation
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func TestFFTGridRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, shape := range [][3]int{{8, 1, 1}, {16, 8, 1}, {8, 4, 16}} {
+		grid := make([]complex128, shape[0]*shape[1]*shape[2])
+		for i := range grid {
+			grid[i] = complex(rng.Float64(), rng.Float64())
+		}
+		orig := append([]complex128(nil), grid...)
+
+		FFTGrid(grid, shape, false, 4)
+		FFTGrid(grid, shape, true, 4)
+		for i := range grid {
+			if v := grid[i] / complex(float64(len(grid)), 0); cmplx.Abs(v-orig[i]) > 1e-12 {
+				t.Fatalf("%v: cell %d is %v, want %v", shape, i, v, orig[i])
+			}
+		}
+	}
+}
+
+func TestParticleMeshFarField(t *testing.T) {
+	tests := []struct {
+		dimensions int
+		assignment string
+		tolerance  float64
+	}{
+		{2, "ngp", 0.15},
+		{2, "cic", 0.03},
+		{2, "tsc", 0.03},
+		{3, "ngp", 0.15},
+		{3, "cic", 0.03},
+		{3, "tsc", 0.03},
+	}
+	const cluster, probes = 50, 8
+	for _, tt := range tests {
+		var universes [2]*Universe
+		for i, solver := range []string{"direct", "particle-mesh"} {
+			universes[i] = newTestUniverse(t, func(u *Universe) {
+				u.Dimensions = tt.dimensions
+				u.SolverName = solver
+				u.Softening.Length = 1
+				u.Mesh.GridSize = 32
+				u.Mesh.Assignment = tt.assignment
+			}, newTestFarField(cluster, probes)...)
+			universes[i].ApplyGravity()
+		}
+
+		direct, mesh := universes[0].Objects, universes[1].Objects
+		var worst float64
+		for i := cluster; i < len(direct); i++ {
+			want := direct[i].Accel
+			worst = math.Max(worst, mesh[i].Accel.Sub(want).Len()/want.Len())
+		}
+		if worst > tt.tolerance {
+			t.Errorf("%dD %s: far field differs %.2f%% from the direct sum", tt.dimensions, tt.assignment, 100*worst)
+		}
+	}
+}
+
+func TestParticleMeshOutlier(t *testing.T) {
+	const cluster, probes = 50, 8
+	far := Coordinates3D{X: 10 * TEST_UNIVERSE_SIZE, Y: -5 * TEST_UNIVERSE_SIZE, Z: TEST_UNIVERSE_SIZE / 2}
+	for _, dimensions := range []int{2, 3} {
+		var universes [2]*Universe
+		for i, solver := range []string{"direct", "particle-mesh"} {
+			objs := append(newTestFarField(cluster, probes), newTestObject(far, Coordinates3D{}, 1, 0.1))
+			universes[i] = newTestUniverse(t, func(u *Universe) {
+				u.Dimensions = dimensions
+				u.SolverName = solver
+				u.Softening.Length = 1
+				u.Mesh.GridSize = 32
+			}, objs...)
+			universes[i].ApplyGravity()
+		}
+
+		// The outlier doesn't stretch the grid away from the cluster
+		direct, mesh := universes[0].Objects, universes[1].Objects
+		var worst float64
+		for i := cluster; i < cluster+probes; i++ {
+			want := direct[i].Accel
+			worst = math.Max(worst, mesh[i].Accel.Sub(want).Len()/want.Len())
+		}
+		if worst > 0.03 {
+			t.Errorf("%dD: far field differs %.2f%% from the direct sum", dimensions, 100*worst)
+		}
+
+		last := len(direct) - 1
+		if want := direct[last].Accel; mesh[last].Accel.Sub(want).Len() > 1e-12*want.Len() {
+			t.Errorf("%dD: outlier accel %v, want the direct sum %v", dimensions, mesh[last].Accel, want)
+		}
+	}
+}
+
+/*
+Returns qtt objects clustered on the center of the universe
+followed by light probes at several cells from them
+*/
+func newTestFarField(qtt, probes int) []*Object {
+	rng := rand.New(rand.NewSource(1))
+	center := Coordinates3D{X: TEST_UNIVERSE_SIZE / 2, Y: TEST_UNIVERSE_SIZE / 2, Z: TEST_UNIVERSE_SIZE / 2}
+	var objs []*Object
+	for i := 0; i < qtt; i++ {
+		d := Coordinates3D{X: rng.Float64() - 0.5, Y: rng.Float64() - 0.5, Z: rng.Float64() - 0.5}
+		objs = append(objs, newTestObject(center.Add(d.Scale(8)), Coordinates3D{}, 1, 0.1))
+	}
+	for i := 0; i < probes; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(probes)
+		d := Coordinates3D{X: math.Cos(angle), Y: math.Sin(angle), Z: 0.5 * math.Sin(3*angle)}
+		objs = append(objs, newTestObject(center.Add(d.Scale(30)), Coordinates3D{}, 1e-9, 0.1))
+	}
+	return objs
+}
@@ -0,0 +1,523 @@
+package simulation
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/Guilherme-De-Marchi/nbody-go/util"
+)
+
+const (
+	DEFAULT_KING_W0          = 5
+	DEFAULT_DISK_PROFILE     = "exponential"
+	DEFAULT_ENCOUNTER_GALAXY = "disk"
+	DEFAULT_MODEL_COLLISION  = "none" // Models are collisionless systems
+
+	// Models without an edge are cut at this amount of scale radii
+	MODEL_MAX_RADIUS = 20
+	// Default radius of the objects of a model, in scale radii
+	MODEL_OBJECT_RADIUS = 0.01
+	// Initial distance of the galaxies of a collision, in scale radii
+	DEFAULT_ENCOUNTER_SEPARATION = 10
+)
+
+/*
+Options of the generation types that follow an astrophysical model.
+
+	total_mass : Mass of every object of the model together (central mass excluded),
+		object_quantity * the middle of mass_range when 0.
+	scale_radius : a of Plummer and Kuzmin, core radius of King, radius of the
+		uniform sphere and scale length of the exponential disk.
+		A tenth of the universe size when 0.
+	king_w0 : Central potential of the King model (W0), concentration grows with it.
+	virial_ratio : 2K/|W| of the uniform sphere, 0 is a cold collapse.
+	disk_profile : exponential or kuzmin.
+	disk_height : Scale height of the disk on 3D universes, the disk is flat when 0.
+	central_mass : Mass of an object added to the center of the disk.
+	dispersion : Random velocities of the disk, as a fraction of the circular velocity.
+	object_radius : Radius of every object of the model, a hundredth of the
+		scale radius when 0. object_radius_range isn't used by the models,
+		objects as large as the model itself would merge right away.
+	encounter : Setup of the galaxy collision.
+*/
+type ModelOpt struct {
+	TotalMass   float64      `json:"total_mass,omitempty"`
+	ScaleRadius float64      `json:"scale_radius,omitempty"`
+	KingW0      float64      `json:"king_w0,omitempty"`
+	VirialRatio float64      `json:"virial_ratio,omitempty"`
+	DiskProfile string       `json:"disk_profile,omitempty"`
+	DiskHeight  float64      `json:"disk_height,omitempty"`
+	CentralMass float64      `json:"central_mass,omitempty"`
+	Dispersion  float64      `json:"dispersion,omitempty"`
+	ObjectRad   float64      `json:"object_radius,omitempty"`
+	Encounter   EncounterOpt `json:"encounter,omitempty"`
+}
+
+/*
+Two systems of the same model set on a collision course.
+
+	galaxy : Model of both systems.
+	mass_ratio : Mass of the second system over the first one, 1 when 0.
+		The second system scale radius grows with the cube root of it.
+	separation : Initial distance between the centers, 10 scale radii when 0.
+	impact_parameter : Offset perpendicular to the approach, 0 is head-on.
+	speed : Relative speed, parabolic orbit when 0.
+	inclination : Degrees between the spins of the systems (3D).
+*/
+type EncounterOpt struct {
+	Galaxy          string  `json:"galaxy,omitempty"`
+	MassRatio       float64 `json:"mass_ratio,omitempty"`
+	Separation      float64 `json:"separation,omitempty"`
+	ImpactParameter float64 `json:"impact_parameter,omitempty"`
+	Speed           float64 `json:"speed,omitempty"`
+	Inclination     float64 `json:"inclination,omitempty"`
+}
+
+var DiskProfiles = []string{"exponential", "kuzmin"}
+
+func (opt *ModelOpt) Init() error {
+	if opt.KingW0 == 0 {
+		opt.KingW0 = DEFAULT_KING_W0
+	}
+	if opt.DiskProfile == "" {
+		opt.DiskProfile = DEFAULT_DISK_PROFILE
+	}
+	if opt.Encounter.Galaxy == "" {
+		opt.Encounter.Galaxy = DEFAULT_ENCOUNTER_GALAXY
+	}
+	if opt.Encounter.MassRatio == 0 {
+		opt.Encounter.MassRatio = 1
+	}
+
+	if opt.KingW0 < 0 || opt.KingW0 > 16 {
+		return fmt.Errorf("invalid king W0 %v, must be between 0 and 16", opt.KingW0)
+	}
+	if !contains(DiskProfiles, opt.DiskProfile) {
+		return fmt.Errorf("invalid disk profile '%s'", opt.DiskProfile)
+	}
+	if _, ok := Models[opt.Encounter.Galaxy]; !ok || opt.Encounter.Galaxy == "galaxy-collision" {
+		return fmt.Errorf("invalid encounter galaxy '%s'", opt.Encounter.Galaxy)
+	}
+	if opt.Encounter.MassRatio < 0 {
+		return fmt.Errorf("encounter mass ratio can't be negative")
+	}
+	if opt.ObjectRad < 0 {
+		return fmt.Errorf("model object radius can't be negative")
+	}
+	return nil
+}
+
+/*
+Generates a system of qtt objects with total mass m and scale radius a,
+moved to the frame of its center of mass so it's centered on the
+origin without bulk motion.
+The spherical models are in equilibrium on 3D universes only,
+on 2D ones the objects are spread over the plane with the same
+radial profile and speeds.
+*/
+type Model interface {
+	GetObjects(u *Universe, opt RandOpt, qtt int, m, a float64) []*Object
+}
+
+var Models = map[string]Model{
+	"plummer":          Plummer{},
+	"king":             King{},
+	"uniform-sphere":   UniformSphere{},
+	"disk":             Disk{},
+	"galaxy-collision": GalaxyCollision{},
+}
+
+// Colors of the first and second systems
+var ModelColors = []color.RGBA{{255, 225, 180, 255}, {170, 200, 255, 255}}
+
+/*
+Adds the objects of the model selected by name to the center
+of the universe, using the random options. The velocity mode
+of the options is ignored, every model sets its own velocities.
+*/
+func (u *Universe) AddModelObjects(name string, opt RandOpt) ([]*Object, error) {
+	model, ok := Models[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid model '%s'", name)
+	}
+
+	m := opt.Model.TotalMass
+	if m == 0 {
+		m = float64(opt.ObjectQtt) * (opt.MassR[0] + opt.MassR[1]) / 2
+	}
+	a := opt.Model.ScaleRadius
+	if a == 0 {
+		a = math.Min(u.Size.X, u.Size.Y) / 10
+	}
+	if m <= 0 || a <= 0 {
+		return nil, fmt.Errorf("model '%s': mass and scale radius must be positive", name)
+	}
+	if opt.Model.ObjectRad == 0 {
+		opt.Model.ObjectRad = MODEL_OBJECT_RADIUS * a
+	}
+
+	objs := model.GetObjects(u, opt, opt.ObjectQtt, m, a)
+	center := u.GetBoxSize().Scale(0.5)
+	for _, obj := range objs {
+		obj.Pos = obj.Pos.Add(center)
+		if u.Dimensions != 3 {
+			obj.Pos.Z, obj.Vel.Z = 0, 0
+		}
+	}
+	u.AddObjects(objs...)
+	return objs, nil
+}
+
+// Moves the objects to the frame of their center of mass
+func toCenterOfMassFrame(objs []*Object) []*Object {
+	var m float64
+	var p, v Coordinates3D
+	for _, obj := range objs {
+		m += obj.Mass
+		p = p.Add(obj.Pos.Scale(obj.Mass))
+		v = v.Add(obj.Vel.Scale(obj.Mass))
+	}
+	if m == 0 {
+		return objs
+	}
+	p, v = p.Scale(1/m), v.Scale(1/m)
+	for _, obj := range objs {
+		obj.Pos = obj.Pos.Sub(p)
+		obj.Vel = obj.Vel.Sub(v)
+	}
+	return objs
+}
+
+func (u *Universe) newModelObject(opt RandOpt, pos, vel Coordinates3D, m float64) *Object {
+	rng := u.GetRand()
+	obj := NewObject(util.RandString(rng, 8), ModelColors[0], pos, m, opt.Model.ObjectRad)
+	obj.Vel = vel
+	return obj
+}
+
+/*
+Plummer sphere, ρ ∝ (1 + r²/a²)^(-5/2).
+Sampled as Aarseth, Hénon & Wielen (1974).
+*/
+type Plummer struct{}
+
+func (Plummer) GetObjects(u *Universe, opt RandOpt, qtt int, m, a float64) []*Object {
+	rng := u.GetRand()
+	objs := make([]*Object, qtt)
+	for i := range objs {
+		// Inverse of the cumulative mass
+		r := math.Inf(1)
+		for r > MODEL_MAX_RADIUS*a {
+			r = a / math.Sqrt(math.Pow(rng.Float64(), -2.0/3)-1)
+		}
+
+		// Speed as a fraction q of the escape speed, g(q) = q²(1-q²)^(7/2)
+		ve := math.Sqrt(2*u.Gconst*m) * math.Pow(r*r+a*a, -0.25)
+		q := rng.Float64()
+		for 0.1*rng.Float64() > q*q*math.Pow(1-q*q, 3.5) {
+			q = rng.Float64()
+		}
+
+		pos := u.GetRandomDirection().Scale(r)
+		vel := u.GetRandomDirection().Scale(q * ve)
+		objs[i] = u.newModelObject(opt, pos, vel, m/float64(qtt))
+	}
+	return toCenterOfMassFrame(objs)
+}
+
+/*
+King (1966) model, a lowered isothermal sphere truncated at the
+tidal radius. a is the core radius r0 = √(9σ²/(4πGρ0)).
+The potential is found by integrating Poisson's equation
+from the center until W = 0.
+*/
+type King struct{}
+
+// King density over the central density, ρ(W)/ρ(W0) with W = (Φt - Φ)/σ²
+func kingDensity(w float64) float64 {
+	if w <= 0 {
+		return 0
+	}
+	return math.Max(math.Exp(w)*math.Erf(math.Sqrt(w))-math.Sqrt(4*w/math.Pi)*(1+2*w/3), 0)
+}
+
+/*
+Radius (in core radii), W and enclosed mass of the King model,
+the mass is in units of 4πρ0r0³
+*/
+type kingProfile struct {
+	X, W, M []float64
+}
+
+// Integrates (1/x²) d(x² dW/dx)/dx = -9ρ(W)/ρ0 with RK4
+func solveKing(w0 float64) kingProfile {
+	rho0 := kingDensity(w0)
+	f := func(x, w, y float64) (float64, float64) {
+		return y, -9*kingDensity(w)/rho0 - 2*y/x
+	}
+
+	// Close to the center W ≈ W0 - 1.5x²
+	x := 1e-4
+	w, y := w0-1.5*x*x, -3*x
+	p := kingProfile{X: []float64{0}, W: []float64{w0}, M: []float64{0}}
+	for {
+		h := 1e-3 * (1 + x)
+		k1w, k1y := f(x, w, y)
+		k2w, k2y := f(x+h/2, w+h/2*k1w, y+h/2*k1y)
+		k3w, k3y := f(x+h/2, w+h/2*k2w, y+h/2*k2y)
+		k4w, k4y := f(x+h, w+h*k3w, y+h*k3y)
+		nw := w + h/6*(k1w+2*k2w+2*k3w+k4w)
+		ny := y + h/6*(k1y+2*k2y+2*k3y+k4y)
+
+		if nw <= 0 {
+			// Tidal radius
+			t := w / (w - nw)
+			x, y, w = x+t*h, y+t*(ny-y), 0
+		} else {
+			x, w, y = x+h, nw, ny
+		}
+		p.X = append(p.X, x)
+		p.W = append(p.W, w)
+		p.M = append(p.M, -x*x*y/9)
+		if w == 0 {
+			return p
+		}
+	}
+}
+
+func (King) GetObjects(u *Universe, opt RandOpt, qtt int, m, a float64) []*Object {
+	rng := u.GetRand()
+	p := solveKing(opt.Model.KingW0)
+	mt := p.M[len(p.M)-1]
+	sigma := math.Sqrt(u.Gconst * m / (9 * a * mt))
+
+	objs := make([]*Object, qtt)
+	for i := range objs {
+		// Inverse of the cumulative mass
+		mr := rng.Float64() * mt
+		j := sort.SearchFloat64s(p.M, mr)
+		if j == 0 {
+			j = 1
+		}
+		if j >= len(p.M) {
+			j = len(p.M) - 1
+		}
+		t := (mr - p.M[j-1]) / (p.M[j] - p.M[j-1])
+		x := p.X[j-1] + t*(p.X[j]-p.X[j-1])
+		w := p.W[j-1] + t*(p.W[j]-p.W[j-1])
+
+		// Speed in units of σ, f(v) ∝ v²(exp(W - v²/2) - 1) up to the escape speed
+		ve := math.Sqrt(2 * w)
+		g := func(v float64) float64 {
+			return v * v * (math.Exp(w-v*v/2) - 1)
+		}
+		var gmax float64
+		for k := 1; k <= 100; k++ {
+			gmax = math.Max(gmax, g(ve*float64(k)/100))
+		}
+		v := ve * rng.Float64()
+		for 1.1*gmax*rng.Float64() > g(v) {
+			v = ve * rng.Float64()
+		}
+
+		pos := u.GetRandomDirection().Scale(x * a)
+		vel := u.GetRandomDirection().Scale(v * sigma)
+		objs[i] = u.newModelObject(opt, pos, vel, m/float64(qtt))
+	}
+	return toCenterOfMassFrame(objs)
+}
+
+/*
+Homogeneous sphere of radius a.
+The objects start at rest unless the virial ratio is set,
+then they get isotropic gaussian velocities with
+σ² = ratio*G*m/(5a) on each axis.
+*/
+type UniformSphere struct{}
+
+func (UniformSphere) GetObjects(u *Universe, opt RandOpt, qtt int, m, a float64) []*Object {
+	rng := u.GetRand()
+	sigma := math.Sqrt(opt.Model.VirialRatio * u.Gconst * m / (5 * a))
+	objs := make([]*Object, qtt)
+	for i := range objs {
+		pos := u.GetRandomDirection().Scale(a * math.Cbrt(rng.Float64()))
+		vel := Coordinates3D{X: rng.NormFloat64(), Y: rng.NormFloat64(), Z: rng.NormFloat64()}.Scale(sigma)
+		objs[i] = u.newModelObject(opt, pos, vel, m/float64(qtt))
+	}
+	return toCenterOfMassFrame(objs)
+}
+
+/*
+Rotating disk on the xy plane with an exponential, Σ ∝ exp(-R/a),
+or Kuzmin, Σ ∝ (1 + R²/a²)^(-3/2), surface density.
+The objects follow the rotation curve of the razor thin disk
+plus the central mass.
+*/
+type Disk struct{}
+
+func (Disk) GetObjects(u *Universe, opt RandOpt, qtt int, m, a float64) []*Object {
+	rng := u.GetRand()
+	mo := opt.Model
+	objs := make([]*Object, 0, qtt+1)
+	if mo.CentralMass > 0 {
+		objs = append(objs, u.newModelObject(opt, Coordinates3D{}, Coordinates3D{}, mo.CentralMass))
+	}
+
+	for i := 0; i < qtt; i++ {
+		r := math.Inf(1)
+		for r > MODEL_MAX_RADIUS*a {
+			if mo.DiskProfile == "kuzmin" {
+				// Inverse of M(R)/M = 1 - a/√(R²+a²)
+				s := 1 - rng.Float64()
+				r = a * math.Sqrt(1/(s*s)-1)
+			} else {
+				// 2πRΣ(R) is a gamma distribution of shape 2
+				r = -a * math.Log((1-rng.Float64())*(1-rng.Float64()))
+			}
+		}
+
+		var z float64
+		if u.Dimensions == 3 && mo.DiskHeight > 0 {
+			// sech² vertical profile
+			z = mo.DiskHeight * math.Atanh(2*rng.Float64()-1)
+			if math.IsInf(z, 0) {
+				z = 0
+			}
+		}
+
+		vc := getDiskCircularVelocity(u.Gconst, m, a, mo.CentralMass, r, mo.DiskProfile)
+		phi := 2 * math.Pi * rng.Float64()
+		pos := Coordinates3D{X: r * math.Cos(phi), Y: r * math.Sin(phi), Z: z}
+		vel := Coordinates3D{X: -vc * math.Sin(phi), Y: vc * math.Cos(phi)}
+		if mo.Dispersion > 0 {
+			d := Coordinates3D{X: rng.NormFloat64(), Y: rng.NormFloat64(), Z: rng.NormFloat64()}
+			vel = vel.Add(d.Scale(mo.Dispersion * vc))
+		}
+		objs = append(objs, u.newModelObject(opt, pos, vel, m/float64(qtt)))
+	}
+	return toCenterOfMassFrame(objs)
+}
+
+/*
+Circular velocity of a razor thin disk of mass m and
+scale radius a plus a central mass mc.
+Exponential (Freeman 1970): v² = 4πGΣ0a y²[I0(y)K0(y) - I1(y)K1(y)], y = R/2a.
+Kuzmin: v² = GmR²/(R²+a²)^(3/2).
+*/
+func getDiskCircularVelocity(gConst, m, a, mc, r float64, profile string) float64 {
+	if r == 0 {
+		return 0
+	}
+	var v2 float64
+	if profile == "kuzmin" {
+		v2 = gConst * m * r * r / math.Pow(r*r+a*a, 1.5)
+	} else {
+		y := r / (2 * a)
+		sigma0 := m / (2 * math.Pi * a * a)
+		v2 = 4 * math.Pi * gConst * sigma0 * a * y * y * (besselI0(y)*besselK0(y) - besselI1(y)*besselK1(y))
+	}
+	v2 += gConst * mc / r
+	return math.Sqrt(math.Max(v2, 0))
+}
+
+// Modified Bessel functions, polynomial approximations of Abramowitz & Stegun 9.8
+
+func besselI0(x float64) float64 {
+	if x <= 3.75 {
+		t := x / 3.75
+		t *= t
+		return 1 + t*(3.5156229+t*(3.0899424+t*(1.2067492+t*(0.2659732+t*(0.0360768+t*0.0045813)))))
+	}
+	t := 3.75 / x
+	return math.Exp(x) / math.Sqrt(x) * (0.39894228 + t*(0.01328592+t*(0.00225319+t*(-0.00157565+t*(0.00916281+
+		t*(-0.02057706+t*(0.02635537+t*(-0.01647633+t*0.00392377))))))))
+}
+
+func besselI1(x float64) float64 {
+	if x <= 3.75 {
+		t := x / 3.75
+		t *= t
+		return x * (0.5 + t*(0.87890594+t*(0.51498869+t*(0.15084934+t*(0.02658733+t*(0.00301532+t*0.00032411))))))
+	}
+	t := 3.75 / x
+	return math.Exp(x) / math.Sqrt(x) * (0.39894228 + t*(-0.03988024+t*(-0.00362018+t*(0.00163801+t*(-0.01031555+
+		t*(0.02282967+t*(-0.02895312+t*(0.01787654-t*0.00420059))))))))
+}
+
+func besselK0(x float64) float64 {
+	if x <= 2 {
+		t := x * x / 4
+		return -math.Log(x/2)*besselI0(x) + (-0.57721566 + t*(0.42278420+t*(0.23069756+t*(0.03488590+t*(0.00262698+
+			t*(0.00010750+t*0.00000740))))))
+	}
+	t := 2 / x
+	return math.Exp(-x) / math.Sqrt(x) * (1.25331414 + t*(-0.07832358+t*(0.02189568+t*(-0.01062446+t*(0.00587872+
+		t*(-0.00251540+t*0.00053208))))))
+}
+
+func besselK1(x float64) float64 {
+	if x <= 2 {
+		t := x * x / 4
+		return math.Log(x/2)*besselI1(x) + (1/x)*(1+t*(0.15443144+t*(-0.67278579+t*(-0.18156897+t*(-0.01919402+
+			t*(-0.00110404-t*0.00004686))))))
+	}
+	t := 2 / x
+	return math.Exp(-x) / math.Sqrt(x) * (1.25331414 + t*(0.23498619+t*(-0.03655620+t*(0.01504268+t*(-0.00780353+
+		t*(0.00325614-t*0.00068245))))))
+}
+
+/*
+Two systems of the encounter galaxy model approaching each other
+on the xy plane, the second one starts on +x moving to -x.
+Both start on the orbit of two point masses around their
+common center of mass.
+*/
+type GalaxyCollision struct{}
+
+func (GalaxyCollision) GetObjects(u *Universe, opt RandOpt, qtt int, m, a float64) []*Object {
+	enc := opt.Model.Encounter
+	model := Models[enc.Galaxy]
+	q := enc.MassRatio
+
+	n1 := int(float64(qtt)/(1+q) + 0.5)
+	m1 := m / (1 + q)
+	g1 := model.GetObjects(u, opt, n1, m1, a)
+	g2 := model.GetObjects(u, opt, qtt-n1, m-m1, a*math.Cbrt(q))
+
+	var ma, mb float64
+	for _, obj := range g1 {
+		ma += obj.Mass
+	}
+	for _, obj := range g2 {
+		mb += obj.Mass
+	}
+	mt := ma + mb
+
+	sep := enc.Separation
+	if sep == 0 {
+		sep = DEFAULT_ENCOUNTER_SEPARATION * a
+	}
+	d := Coordinates3D{X: sep, Y: enc.ImpactParameter}
+	speed := enc.Speed
+	if speed == 0 {
+		speed = math.Sqrt(2 * u.Gconst * mt / d.Len())
+	}
+	v := Coordinates3D{X: -speed}
+
+	incl := enc.Inclination * math.Pi / 180
+	for _, obj := range g1 {
+		obj.Pos = obj.Pos.Sub(d.Scale(mb / mt))
+		obj.Vel = obj.Vel.Sub(v.Scale(mb / mt))
+	}
+	for _, obj := range g2 {
+		if u.Dimensions == 3 {
+			obj.Pos, obj.Vel = obj.Pos.RotateX(incl), obj.Vel.RotateX(incl)
+		}
+		obj.Pos = obj.Pos.Add(d.Scale(ma / mt))
+		obj.Vel = obj.Vel.Add(v.Scale(ma / mt))
+		obj.Color = ModelColors[1]
+	}
+	return append(g1, g2...)
+}
@@ -0,0 +1,57 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestModels(t *testing.T) {
+	for _, dimensions := range []int{2, 3} {
+		for _, name := range []string{"plummer", "king", "uniform-sphere", "disk", "galaxy-collision"} {
+			opt := RandOpt{
+				MassR:     [2]float64{1, 1},
+				ObjectQtt: 200,
+				Seed:      1,
+				Model:     ModelOpt{ScaleRadius: 10},
+			}
+			if err := opt.Init(); err != nil {
+				t.Fatal(err)
+			}
+			u := newTestUniverse(t, func(u *Universe) {
+				u.Dimensions = dimensions
+				u.CollisionName = DEFAULT_MODEL_COLLISION
+				u.IntegratorName = "leapfrog"
+				u.Dt = 0.1
+				u.Softening.Length = 0.1
+				u.SetSeed(opt.Seed)
+			})
+			objs, err := u.AddModelObjects(name, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(objs) < opt.ObjectQtt {
+				t.Errorf("%dD %s: %d objects generated, want at least %d", dimensions, name, len(objs), opt.ObjectQtt)
+			}
+			if r := objs[0].Radius; r != MODEL_OBJECT_RADIUS*opt.Model.ScaleRadius {
+				t.Errorf("%dD %s: objects of radius %v, want %v", dimensions, name, r, MODEL_OBJECT_RADIUS*opt.Model.ScaleRadius)
+			}
+			d := u.GetDiagnostics()
+			if com := d.CenterOfMass.Sub(u.GetBoxSize().Scale(0.5)); com.Len() > 1e-9 || d.Momentum.Len() > 1e-9*d.MomentumScale {
+				t.Errorf("%dD %s: center of mass %v away from the center, momentum %v", dimensions, name, com, d.Momentum)
+			}
+
+			for i := 0; i < 20; i++ {
+				u.Step()
+			}
+			if len(u.Objects) != len(objs) {
+				t.Errorf("%dD %s: %d objects left of %d after 20 steps", dimensions, name, len(u.Objects), len(objs))
+			}
+			for _, obj := range u.Objects {
+				if p := obj.Pos; math.IsNaN(p.X + p.Y + p.Z) {
+					t.Fatalf("%dD %s: object at %v", dimensions, name, p)
+				}
+			}
+		}
+	}
+}
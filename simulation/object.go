@@ -2,19 +2,24 @@ package simulation
 
 import (
 	"image/color"
+	"math/rand"
 
 	"github.com/Guilherme-De-Marchi/nbody-go/util"
 )
 
 type Object struct {
-	Name                string
-	Color               color.RGBA
-	Pos                 Coordinates2D
-	Mass, Accel, Radius float64
-	Vel, Momentum       Vector2
+	Name   string        `json:"name,omitempty"`
+	Color  color.RGBA    `json:"color,omitempty"`
+	Pos    Coordinates3D `json:"position,omitempty"`
+	Vel    Coordinates3D `json:"velocity,omitempty"`
+	Accel  Coordinates3D `json:"acceleration,omitempty"`
+	Mass   float64       `json:"mass,omitempty"`
+	Radius float64       `json:"radius,omitempty"`
+
+	Trail *Trail `json:"-"` // Last positions, only recorded while the trails are shown
 }
 
-func NewObject(name string, color color.RGBA, pos Coordinates2D, mass, radius float64) *Object {
+func NewObject(name string, color color.RGBA, pos Coordinates3D, mass, radius float64) *Object {
 	return &Object{
 		Name:   name,
 		Color:  color,
@@ -24,16 +29,21 @@ func NewObject(name string, color color.RGBA, pos Coordinates2D, mass, radius fl
 	}
 }
 
-func GetRandomObjects(posR Coordinates2D, massR, radR [2]float64, qtt int) []*Object {
+func GetRandomObjects(rng *rand.Rand, posR Coordinates3D, massR, radR [2]float64, qtt int) []*Object {
 	objs := make([]*Object, qtt)
 	for i := range objs {
-		m := util.RandFloatRange(massR[0], massR[1])
-		r := util.RandFloatRange(radR[0], radR[1])
+		m := util.RandFloatRange(rng, massR[0], massR[1])
+		r := util.RandFloatRange(rng, radR[0], radR[1])
 		c := util.IntToRgbRange(int(m), int(massR[1]))
+		pos := Coordinates3D{
+			X: util.RandFloatRange(rng, 0, posR.X),
+			Y: util.RandFloatRange(rng, 0, posR.Y),
+			Z: util.RandFloatRange(rng, 0, posR.Z),
+		}
 		objs[i] = &Object{
-			Name:   util.RandString(8),
+			Name:   util.RandString(rng, 8),
 			Color:  c,
-			Pos:    Coordinates2D{util.RandFloatRange(0, posR.X), util.RandFloatRange(0, posR.Y)},
+			Pos:    pos,
 			Mass:   m,
 			Radius: r,
 		}
@@ -42,68 +52,99 @@ func GetRandomObjects(posR Coordinates2D, massR, radR [2]float64, qtt int) []*Ob
 }
 
 func (obj *Object) GetDistance(tar *Object) float64 {
-	return CalcDistance(obj.Pos.X, tar.Pos.X, obj.Pos.Y, tar.Pos.Y)
+	return tar.Pos.Sub(obj.Pos).Len()
 }
 
 func (obj *Object) GetAbsDistance(tar *Object) float64 {
-	return CalcAbsDistance(obj.Pos.X, tar.Pos.X, obj.Pos.Y, tar.Pos.Y)
+	return obj.GetDistance(tar)
 }
 
-func (obj *Object) GetGravitationalForce(tar *Object, gConst float64) Vector2 {
+func (obj *Object) GetGravitationalForce(tar *Object, gConst float64, soft Softening) Vector2 {
 	d := obj.GetDistance(tar)
 	return Vector2{
 		Direction: obj.GetVectorDirection(tar),
-		Magnitude: CalcGravitationalForce(obj.Mass, tar.Mass, d, gConst),
+		Magnitude: soft.CalcGravitationalForce(obj.Mass, tar.Mass, d, gConst),
 	}
 }
 
-// func (obj *Object) GetMomentum() Vector2 {
-// 	return CalcMomentum(obj.Accel, obj.Mass)
-// }
+func (obj *Object) GetPotentialEnergy(tar *Object, gConst float64, soft Softening) float64 {
+	return soft.CalcPotentialEnergy(obj.Mass, tar.Mass, obj.GetDistance(tar), gConst)
+}
+
+func (obj *Object) GetMomentum() Coordinates3D {
+	return obj.Vel.Scale(obj.Mass)
+}
+
+/*
+Returns the acceleration vector caused on the object
+by the gravitational pull of the target
+*/
+func (obj *Object) GetGravitationalAcceleration(tar *Object, gConst float64, soft Softening) Coordinates3D {
+	d := tar.Pos.Sub(obj.Pos)
+	return d.Scale(gConst * tar.Mass * soft.ForceKernel(d.Len()))
+}
 
 /*
 Reduces the distance between two objects to 1
 and returns the proportional x and y changes
 */
-func (obj *Object) GetVectorDirection(tar *Object) Coordinates2D {
+func (obj *Object) GetVectorDirection(tar *Object) Coordinates3D {
 	d := obj.GetDistance(tar)
-	lx := tar.Pos.X - obj.Pos.X
-	ly := tar.Pos.Y - obj.Pos.Y
-
-	// log.Println("Direction X:", CalcProportionalLeg(d, 1, lx))
-	// log.Println("Direction Y:", CalcProportionalLeg(d, 1, ly))
-	return Coordinates2D{
-		X: CalcProportionalLeg(d, 1, lx),
-		Y: CalcProportionalLeg(d, 1, ly),
+	l := tar.Pos.Sub(obj.Pos)
+
+	// log.Println("Direction X:", CalcProportionalLeg(d, 1, l.X))
+	// log.Println("Direction Y:", CalcProportionalLeg(d, 1, l.Y))
+	return Coordinates3D{
+		X: CalcProportionalLeg(d, 1, l.X),
+		Y: CalcProportionalLeg(d, 1, l.Y),
+		Z: CalcProportionalLeg(d, 1, l.Z),
 	}
 }
 
 /*
-Changes the object velocity and acceleration
-based on the force applied
+Returns the acceleration caused on the object by the target,
+ignoring the object itself and coincident targets.
+Overlapping objects are resolved by Universe.HandleCollisions.
 */
-func (obj *Object) ApplyForce(f Vector2, tar *Object) {
-	obj.Vel.Direction = f.Direction
-	obj.Accel = obj.GetResultingAcceleration(f, tar)
-	obj.Vel.Magnitude += obj.Accel
+func (obj *Object) GetPairAcceleration(tar *Object, gConst float64, soft Softening) Coordinates3D {
+	if tar == obj || tar.Pos == obj.Pos {
+		return Coordinates3D{}
+	}
+	return obj.GetGravitationalAcceleration(tar, gConst, soft)
+}
 
-	obj.SetPos(obj.GetResultingPos())
+/*
+Same as GetPairAcceleration but pulled by the nearest image
+of the target. period is the size of a periodic universe,
+the axes with a zero period don't wrap.
+*/
+func (obj *Object) GetImageAcceleration(tar *Object, period Coordinates3D, gConst float64, soft Softening) Coordinates3D {
+	if tar == obj {
+		return Coordinates3D{}
+	}
+	d := MinimumImage(tar.Pos.Sub(obj.Pos), period)
+	if d == (Coordinates3D{}) {
+		return d
+	}
+	return d.Scale(gConst * tar.Mass * soft.ForceKernel(d.Len()))
 }
 
-func (obj *Object) GetResultingAcceleration(f Vector2, tar *Object) float64 {
-	if obj.GetDistance(tar) <= obj.Radius+tar.Radius {
-		// Detect collision
-		// Add momentum
-		obj.Vel.Magnitude = 0
-		return 0
+/*
+Returns the vector sum of the accelerations caused
+by every target on the object
+*/
+func (obj *Object) GetResultingAcceleration(tars []*Object, gConst float64, soft Softening) Coordinates3D {
+	var accel Coordinates3D
+	for _, tar := range tars {
+		accel = accel.Add(obj.GetPairAcceleration(tar, gConst, soft))
 	}
-	return CalcAcceleration(f, obj.Mass)
+	return accel
 }
 
-func (obj *Object) GetResultingPos() Coordinates2D {
-	return CalcResultingPosition(obj.Pos, obj.Vel, obj.Accel)
+func (obj *Object) GetResultingPos() Coordinates3D {
+	return CalcResultingPosition(obj.Pos, obj.Vel)
 }
 
-func (obj *Object) SetPos(pos Coordinates2D) {
+func (obj *Object) SetPos(pos Coordinates3D) {
 	obj.Pos = pos
 }
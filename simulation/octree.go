@@ -0,0 +1,180 @@
+package simulation
+
+import (
+	"math"
+)
+
+// Objects closer than the smallest node are kept on the same leaf
+const OCTREE_MAX_DEPTH = 32
+
+/*
+Node of the Barnes-Hut octree.
+Com is a pseudo object placed on the center of mass of the node
+holding the total mass of the objects inside it.
+On 2D universes every object has the same z, only the four
+children of one side are used and the tree is a quadtree.
+*/
+type Octree struct {
+	Center   Coordinates3D
+	Half     float64 // Half of the side of the node
+	Com      Object
+	Children [8]*Octree
+	Objects  []*Object // Only used by leaves
+
+	leaf bool
+}
+
+func NewOctree(objs []*Object) *Octree {
+	if len(objs) == 0 {
+		return &Octree{leaf: true}
+	}
+
+	min, max := objs[0].Pos, objs[0].Pos
+	for _, obj := range objs[1:] {
+		min.X = math.Min(min.X, obj.Pos.X)
+		min.Y = math.Min(min.Y, obj.Pos.Y)
+		min.Z = math.Min(min.Z, obj.Pos.Z)
+		max.X = math.Max(max.X, obj.Pos.X)
+		max.Y = math.Max(max.Y, obj.Pos.Y)
+		max.Z = math.Max(max.Z, obj.Pos.Z)
+	}
+
+	// Slightly bigger than the bounding box so the objects
+	// on the border fall inside the root
+	half := math.Max(max.X-min.X, math.Max(max.Y-min.Y, max.Z-min.Z))/2*1.001 + 1
+	t := &Octree{
+		Center: min.Add(max).Scale(0.5),
+		Half:   half,
+		leaf:   true,
+	}
+	for _, obj := range objs {
+		t.insert(obj, 0)
+	}
+	t.calcMass()
+	return t
+}
+
+func (t *Octree) insert(obj *Object, depth int) {
+	if t.leaf {
+		if len(t.Objects) == 0 || depth >= OCTREE_MAX_DEPTH {
+			t.Objects = append(t.Objects, obj)
+			return
+		}
+
+		old := t.Objects
+		t.Objects = nil
+		t.leaf = false
+		for _, o := range old {
+			t.getChild(o).insert(o, depth+1)
+		}
+	}
+	t.getChild(obj).insert(obj, depth+1)
+}
+
+/*
+Returns the octant of the node where the object is,
+creating it if needed
+*/
+func (t *Octree) getChild(obj *Object) *Octree {
+	var i int
+	c := t.Center
+	h := t.Half / 2
+	if obj.Pos.X >= t.Center.X {
+		i |= 1
+		c.X += h
+	} else {
+		c.X -= h
+	}
+	if obj.Pos.Y >= t.Center.Y {
+		i |= 2
+		c.Y += h
+	} else {
+		c.Y -= h
+	}
+	if obj.Pos.Z >= t.Center.Z {
+		i |= 4
+		c.Z += h
+	} else {
+		c.Z -= h
+	}
+
+	if t.Children[i] == nil {
+		t.Children[i] = &Octree{Center: c, Half: h, leaf: true}
+	}
+	return t.Children[i]
+}
+
+func (t *Octree) calcMass() {
+	var m float64
+	var p Coordinates3D
+	if t.leaf {
+		for _, obj := range t.Objects {
+			m += obj.Mass
+			p = p.Add(obj.Pos.Scale(obj.Mass))
+		}
+	} else {
+		for _, c := range t.Children {
+			if c == nil {
+				continue
+			}
+			c.calcMass()
+			m += c.Com.Mass
+			p = p.Add(c.Com.Pos.Scale(c.Com.Mass))
+		}
+	}
+
+	t.Com.Mass = m
+	if m != 0 {
+		t.Com.Pos = p.Scale(1 / m)
+	} else {
+		t.Com.Pos = t.Center
+	}
+}
+
+/*
+Returns the acceleration caused on the object by the node.
+A node is treated as a single body when side/distance < theta.
+period is the size of a periodic universe (see GetImageAcceleration),
+the nodes as big as half of it are always opened.
+*/
+func (t *Octree) GetAcceleration(obj *Object, theta, gConst float64, soft Softening, period Coordinates3D) Coordinates3D {
+	var accel Coordinates3D
+	if t.Com.Mass == 0 {
+		return accel
+	}
+
+	if t.leaf {
+		for _, tar := range t.Objects {
+			accel = accel.Add(obj.GetImageAcceleration(tar, period, gConst, soft))
+		}
+		return accel
+	}
+
+	// The object inside the node would pull itself
+	if !t.contains(obj.Pos) && t.isDistant(obj, theta, period) {
+		return obj.GetImageAcceleration(&t.Com, period, gConst, soft)
+	}
+
+	for _, c := range t.Children {
+		if c != nil {
+			accel = accel.Add(c.GetAcceleration(obj, theta, gConst, soft, period))
+		}
+	}
+	return accel
+}
+
+func (t *Octree) isDistant(obj *Object, theta float64, period Coordinates3D) bool {
+	side := 2 * t.Half
+	for _, p := range [...]float64{period.X, period.Y, period.Z} {
+		if p > 0 && side >= p/2 {
+			return false
+		}
+	}
+	return side < theta*MinimumImage(t.Com.Pos.Sub(obj.Pos), period).Len()
+}
+
+func (t *Octree) contains(pos Coordinates3D) bool {
+	return math.Abs(pos.X-t.Center.X) <= t.Half &&
+		math.Abs(pos.Y-t.Center.Y) <= t.Half &&
+		math.Abs(pos.Z-t.Center.Z) <= t.Half
+}
@@ -0,0 +1,21 @@
+package simulation
+
+// Whether s is one of the names in list
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the name that comes after name in list, cycling back to the first one
+func NextName(list []string, name string) string {
+	for i, n := range list {
+		if n == name {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
+}
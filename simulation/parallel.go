@@ -0,0 +1,65 @@
+package simulation
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Amount of objects taken at once by each worker
+const PARALLEL_CHUNK = 64
+
+/*
+Calls f for every index in [0, n) distributing the indexes
+in chunks between the given amount of goroutines.
+Runs on the calling goroutine when workers <= 1.
+*/
+func ParallelFor(n, workers int, f func(i int)) {
+	if workers <= 1 || n <= PARALLEL_CHUNK {
+		for i := 0; i < n; i++ {
+			f(i)
+		}
+		return
+	}
+
+	var next int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				end := int(atomic.AddInt64(&next, PARALLEL_CHUNK))
+				start := end - PARALLEL_CHUNK
+				if start >= n {
+					return
+				}
+				if end > n {
+					end = n
+				}
+				for i := start; i < end; i++ {
+					f(i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Amount of goroutines used by the universe, 1 unless the parallel mode is on
+func (u *Universe) getWorkers() int {
+	if u.Parallel {
+		return u.Workers
+	}
+	return 1
+}
+
+/*
+Calls f for every object, in parallel when the universe
+parallel mode is on.
+f must only change the object it receives.
+*/
+func (u *Universe) ForEachObject(objs []*Object, f func(obj *Object)) {
+	ParallelFor(len(objs), u.getWorkers(), func(i int) {
+		f(objs[i])
+	})
+}
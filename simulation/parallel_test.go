@@ -0,0 +1,36 @@
+package simulation
+
+import "testing"
+
+func TestParallelMatchesSerial(t *testing.T) {
+	for _, dimensions := range []int{2, 3} {
+		for _, solver := range ForceSolverNames {
+			for _, integrator := range IntegratorNames {
+				universes := [2]*Universe{}
+				for i, parallel := range []bool{false, true} {
+					universes[i] = newTestUniverse(t, func(u *Universe) {
+						u.Dimensions = dimensions
+						u.SolverName = solver
+						u.IntegratorName = integrator
+						u.Parallel = parallel
+						u.Workers = 4
+						u.Mesh.GridSize = 16
+					}, newTestCluster(300, 1)...)
+					for s := 0; s < 3; s++ {
+						universes[i].Step()
+					}
+				}
+
+				serial, parallel := universes[0], universes[1]
+				for i, obj := range serial.Objects {
+					p := parallel.Objects[i]
+					if obj.Pos != p.Pos || obj.Vel != p.Vel || obj.Accel != p.Accel {
+						t.Errorf("%dD %s %s: object %d differs, serial %v %v, parallel %v %v",
+							dimensions, solver, integrator, i, obj.Pos, obj.Vel, p.Pos, p.Vel)
+						break
+					}
+				}
+			}
+		}
+	}
+}
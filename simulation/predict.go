@@ -0,0 +1,42 @@
+package simulation
+
+/*
+Returns a copy of the universe with copies of its objects.
+The copy doesn't keep trails nor diagnostics and doesn't log.
+*/
+func (u *Universe) Clone() *Universe {
+	c := *u
+	c.Objects = make([]*Object, len(u.Objects))
+	for i, obj := range u.Objects {
+		o := *obj
+		o.Trail = nil
+		c.Objects[i] = &o
+	}
+	c.Reference = nil
+	c.CenterOfMassTrail = nil
+	c.meshGrid = nil
+	c.quiet = true
+	return &c
+}
+
+/*
+Steps a copy of the universe with obj added to it and returns
+the positions obj goes through, one per step.
+Collisions are ignored and the universe is left untouched.
+*/
+func (u *Universe) PredictTrajectory(obj *Object, steps int) []Coordinates3D {
+	c := u.Clone()
+	c.SetCollision("none")
+	o := *obj
+	o.Trail = nil
+	c.AddObjects(&o)
+	c.ApplyGravity()
+
+	path := make([]Coordinates3D, 0, steps+1)
+	path = append(path, o.Pos)
+	for i := 0; i < steps; i++ {
+		c.Step()
+		path = append(path, o.Pos)
+	}
+	return path
+}
@@ -0,0 +1,103 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math/rand"
+	"os"
+
+	"github.com/Guilherme-De-Marchi/nbody-go/util"
+)
+
+// Explicit description of an object
+type ObjectConf struct {
+	Name   string        `json:"name,omitempty"`
+	Color  string        `json:"color,omitempty"` // "#rrggbb"
+	Pos    Coordinates3D `json:"position,omitempty"`
+	Vel    Coordinates3D `json:"velocity,omitempty"`
+	Mass   float64       `json:"mass,omitempty"`
+	Radius float64       `json:"radius,omitempty"`
+}
+
+/*
+Options of the "prefab" generation type.
+Scenario is the path of a scenario file.
+*/
+type PrefabOpt struct {
+	Scenario string       `json:"scenario,omitempty"`
+	Objects  []ObjectConf `json:"objects,omitempty"`
+}
+
+/*
+Content of a scenario file.
+Universe holds only the settings that must be overridden.
+*/
+type Scenario struct {
+	Universe *Universe    `json:"universe,omitempty"`
+	Objects  []ObjectConf `json:"objects,omitempty"`
+}
+
+// Objects without name get a random one taken from rng
+func (conf ObjectConf) GetObject(rng *rand.Rand) (*Object, error) {
+	if conf.Mass <= 0 {
+		return nil, fmt.Errorf("object '%s': mass must be positive", conf.Name)
+	}
+	if conf.Radius < 0 {
+		return nil, fmt.Errorf("object '%s': radius can't be negative", conf.Name)
+	}
+
+	c := color.RGBA{255, 255, 255, 255}
+	if conf.Color != "" {
+		var err error
+		if c, err = util.HexToRgb(conf.Color); err != nil {
+			return nil, fmt.Errorf("object '%s': %v", conf.Name, err)
+		}
+	}
+
+	name := conf.Name
+	if name == "" {
+		name = util.RandString(rng, 8)
+	}
+
+	obj := NewObject(name, c, conf.Pos, conf.Mass, conf.Radius)
+	obj.Vel = conf.Vel
+	return obj, nil
+}
+
+func GetObjects(rng *rand.Rand, confs []ObjectConf) ([]*Object, error) {
+	objs := make([]*Object, len(confs))
+	for i, conf := range confs {
+		obj, err := conf.GetObject(rng)
+		if err != nil {
+			return nil, err
+		}
+		objs[i] = obj
+	}
+	return objs, nil
+}
+
+/*
+Returns the objects listed on the options followed by the ones
+of the scenario file. The universe settings present on the
+scenario file override the ones of u.
+*/
+func (opt PrefabOpt) GetObjects(u *Universe) ([]*Object, error) {
+	confs := opt.Objects
+	if opt.Scenario != "" {
+		b, err := os.ReadFile(opt.Scenario)
+		if err != nil {
+			return nil, err
+		}
+		sc := Scenario{Universe: u}
+		if err := json.Unmarshal(b, &sc); err != nil {
+			return nil, fmt.Errorf("scenario '%s': %v", opt.Scenario, err)
+		}
+		confs = append(confs[:len(confs):len(confs)], sc.Objects...)
+	}
+
+	if len(confs) == 0 {
+		return nil, fmt.Errorf("no objects listed on prefab options")
+	}
+	return GetObjects(u.GetRand(), confs)
+}
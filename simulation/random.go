@@ -0,0 +1,131 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/Guilherme-De-Marchi/nbody-go/util"
+)
+
+const DEFAULT_VELOCITY_MODE = "rest"
+
+/*
+Velocity modes of the random objects:
+
+	rest : Objects start stopped.
+	uniform : Random direction and speed inside the velocity range.
+	circular : Circular orbit around the center of mass of the universe.
+	disk : Rigid rotation around the center of mass with the angular velocity.
+
+On 3D universes the orbits and the rotation are around the z axis.
+*/
+var VelocityModes = []string{"rest", "uniform", "circular", "disk"}
+
+// Seed taken from the clock, used when no seed is set
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+func (opt *RandOpt) Init() error {
+	if opt.Seed == 0 {
+		opt.Seed = NewSeed()
+	}
+	if opt.VelMode == "" {
+		opt.VelMode = DEFAULT_VELOCITY_MODE
+	}
+	if !contains(VelocityModes, opt.VelMode) {
+		return fmt.Errorf("invalid velocity mode '%s'", opt.VelMode)
+	}
+	return opt.Model.Init()
+}
+
+/*
+Restarts the random source of the universe, the same seed
+always generates the same objects
+*/
+func (u *Universe) SetSeed(seed int64) {
+	u.Seed = seed
+	u.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Returns the random source, seeded from the clock when unset
+func (u *Universe) GetRand() *rand.Rand {
+	if u.Rand == nil {
+		u.SetSeed(NewSeed())
+	}
+	return u.Rand
+}
+
+/*
+Adds qtt random objects to the universe, giving them
+the initial velocity set by the options
+*/
+func (u *Universe) AddRandomObjects(opt RandOpt, qtt int) []*Object {
+	objs := GetRandomObjects(u.GetRand(), u.Size, opt.MassR, opt.RadR, qtt)
+	u.AddObjects(objs...)
+	u.SetRandomVelocities(objs, opt)
+	return objs
+}
+
+// Random unit vector, on the xy plane for 2D universes
+func (u *Universe) GetRandomDirection() Coordinates3D {
+	a := util.RandFloatRange(u.GetRand(), 0, 2*math.Pi)
+	if u.Dimensions != 3 {
+		return Coordinates3D{X: math.Cos(a), Y: math.Sin(a)}
+	}
+	z := util.RandFloatRange(u.GetRand(), -1, 1)
+	r := math.Sqrt(1 - z*z)
+	return Coordinates3D{X: r * math.Cos(a), Y: r * math.Sin(a), Z: z}
+}
+
+/*
+Sets the velocity of the objects following the velocity mode
+of the options. The objects must already be on the universe
+since the circular and disk modes depend on all of them.
+*/
+func (u *Universe) SetRandomVelocities(objs []*Object, opt RandOpt) {
+	switch opt.VelMode {
+	case "uniform":
+		for _, obj := range objs {
+			v := util.RandFloatRange(u.GetRand(), opt.VelR[0], opt.VelR[1])
+			obj.Vel = u.GetRandomDirection().Scale(v)
+		}
+
+	case "circular":
+		// v = √(a*r) using the component of the acceleration
+		// pointing to the center of mass
+		com, comVel := u.GetCenterOfMass()
+		u.ApplyGravityTo(objs)
+		for _, obj := range objs {
+			d := obj.Pos.Sub(com)
+			r := d.Len()
+			if r == 0 {
+				obj.Vel = comVel
+				continue
+			}
+			ar := -obj.Accel.Dot(d) / r
+			v := math.Sqrt(math.Max(ar, 0) * r)
+			t := Coordinates3D{X: -d.Y, Y: d.X}
+			if tl := t.Len(); tl > 0 {
+				obj.Vel = comVel.Add(t.Scale(v / tl))
+			} else {
+				obj.Vel = comVel
+			}
+		}
+
+	case "disk":
+		// v = ω x r
+		com, comVel := u.GetCenterOfMass()
+		for _, obj := range objs {
+			d := obj.Pos.Sub(com)
+			obj.Vel = comVel.Add(Coordinates3D{X: -d.Y, Y: d.X}.Scale(opt.AngularVel))
+		}
+
+	default:
+		for _, obj := range objs {
+			obj.Vel = Coordinates3D{}
+		}
+	}
+}
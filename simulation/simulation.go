@@ -2,14 +2,17 @@ package simulation
 
 import (
 	"image"
-
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 type RandOpt struct {
-	MassR     [2]float64 `json:"mass_range,omitempty"`
-	RadR      [2]float64 `json:"object_radius_range,omitempty"`
-	ObjectQtt int        `json:"object_quantity,omitempty"`
+	MassR      [2]float64 `json:"mass_range,omitempty"`
+	RadR       [2]float64 `json:"object_radius_range,omitempty"`
+	ObjectQtt  int        `json:"object_quantity,omitempty"`
+	VelMode    string     `json:"velocity_mode,omitempty"`
+	VelR       [2]float64 `json:"velocity_range,omitempty"`
+	AngularVel float64    `json:"angular_velocity,omitempty"`
+	Seed       int64      `json:"seed,omitempty"` // Taken from the clock when 0
+	Model      ModelOpt   `json:"model,omitempty"`
 }
 
 type EditOpt struct {
@@ -24,28 +27,79 @@ type EditOpt struct {
 	GradExpDesloc float64       `json:"gradient_exp_desloc,omitempty"`
 	ObjectsDesloc int           `json:"object_quantity_desloc,omitempty"`
 	GconstDesloc  float64       `json:"gravitational_const_desloc,omitempty"`
+	DtDesloc      float64       `json:"dt_desloc,omitempty"`
+	MassDesloc    float64       `json:"mass_desloc,omitempty"`
+	RadiusDesloc  float64       `json:"radius_desloc,omitempty"`
 	Zoom          float64       `json:"initial_zoom,omitempty"`
 	ZoomDesloc    float64       `json:"zoom_desloc,omitempty"`
-	Offset        Coordinates2D `json:"initial_offset,omitempty"`
+	Offset        Coordinates3D `json:"initial_offset,omitempty"`
 	OffsetDesloc  float64       `json:"offset_desloc,omitempty"`
+	StatePath     string        `json:"state_path,omitempty"`
+	ShowPlots     bool          `json:"show_plots,omitempty"`
+	PlotWindow    float64       `json:"plot_window,omitempty"` // Simulated time shown on the plots
+	CameraMode    string        `json:"camera_mode,omitempty"`
+	ShowTrails    bool          `json:"show_trails,omitempty"`
+	TrailLength   int           `json:"trail_length,omitempty"` // Positions kept by each trail
+	TrailEvery    int           `json:"trail_every,omitempty"`  // Steps between two positions
+	TrailFrame    string        `json:"trail_frame,omitempty"`
+	PlaceMass     float64       `json:"place_mass,omitempty"`
+	PlaceRadius   float64       `json:"place_radius,omitempty"`
+	PlaceVelScale float64       `json:"place_velocity_scale,omitempty"` // Velocity per unit of dragged distance
+	PredictSteps  int           `json:"predict_steps,omitempty"`
+	Projection    string        `json:"projection,omitempty"`
+	ViewYaw       float64       `json:"view_yaw,omitempty"`   // Rotation of the 3D view, in radians
+	ViewPitch     float64       `json:"view_pitch,omitempty"` // Rotation of the 3D view, in radians
 }
 
 type Simulation struct {
-	Universe *Universe
+	Universe       *Universe
+	GenerationType string // Followed when a new universe is generated
 
-	RandOpt RandOpt
-	EditOpt EditOpt
+	RandOpt   RandOpt
+	EditOpt   EditOpt
+	OutputOpt OutputOpt
+	Recorder  *Recorder // Not nil while recording
+	Selected  *Object
+	Companion *Object // Second object of the co-rotating frame
 
 	WinGradientImage   *image.RGBA
 	TotalGradientImage *image.RGBA
-	Keys               []ebiten.Key
 }
 
-func NewSimulation(u *Universe, randOpt RandOpt, editOpt EditOpt) *Simulation {
+func NewSimulation(u *Universe, randOpt RandOpt, editOpt EditOpt, outputOpt OutputOpt) *Simulation {
 	return &Simulation{
-		Universe: u,
-		RandOpt:  randOpt,
-		EditOpt:  editOpt,
-		Keys:     []ebiten.Key{},
+		Universe:  u,
+		RandOpt:   randOpt,
+		EditOpt:   editOpt,
+		OutputOpt: outputOpt,
+	}
+}
+
+/*
+Replaces the objects of the universe by new ones generated from
+the seed, with the model of the generation type when it's one
+of Models and uniformly random otherwise
+*/
+func (s *Simulation) Regenerate(seed int64) error {
+	s.RandOpt.Seed = seed
+	s.Universe.Reset()
+	s.Universe.SetSeed(seed)
+	if _, ok := Models[s.GenerationType]; ok {
+		_, err := s.Universe.AddModelObjects(s.GenerationType, s.RandOpt)
+		return err
 	}
+	s.Universe.AddRandomObjects(s.RandOpt, s.RandOpt.ObjectQtt)
+	return nil
+}
+
+// Returns the selected object, nil when it's no longer on the universe
+func (s *Simulation) GetSelected() *Object {
+	s.Selected = s.Universe.Find(s.Selected)
+	return s.Selected
+}
+
+// Same as GetSelected for the companion
+func (s *Simulation) GetCompanion() *Object {
+	s.Companion = s.Universe.Find(s.Companion)
+	return s.Companion
 }
@@ -0,0 +1,254 @@
+package simulation
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const DEFAULT_SNAPSHOT_FORMAT = "csv"
+
+/*
+Writes the state of the universe objects.
+Close flushes the pending data, it doesn't close the io.Writer.
+*/
+type SnapshotWriter interface {
+	WriteSnapshot(u *Universe) error
+	Close() error
+}
+
+var SnapshotFormats = map[string]func(w io.Writer) SnapshotWriter{
+	"csv":    NewCSVWriter,
+	"jsonl":  NewJSONLWriter,
+	"binary": NewBinaryWriter,
+}
+
+// Format used by each file extension
+var SnapshotExtensions = map[string]string{
+	".csv":   "csv",
+	".jsonl": "jsonl",
+	".bin":   "binary",
+}
+
+func NewSnapshotWriter(format string, w io.Writer) (SnapshotWriter, error) {
+	f, ok := SnapshotFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid snapshot format '%s'", format)
+	}
+	return f(w), nil
+}
+
+/*
+One row per object:
+time,name,x,y,z,vx,vy,vz,mass,radius
+*/
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func NewCSVWriter(w io.Writer) SnapshotWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *CSVWriter) WriteSnapshot(u *Universe) error {
+	if !cw.header {
+		cw.header = true
+		cw.w.Write([]string{"time", "name", "x", "y", "z", "vx", "vy", "vz", "mass", "radius"})
+	}
+
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	t := f(u.Time)
+	for _, obj := range u.Objects {
+		cw.w.Write([]string{
+			t, obj.Name,
+			f(obj.Pos.X), f(obj.Pos.Y), f(obj.Pos.Z), f(obj.Vel.X), f(obj.Vel.Y), f(obj.Vel.Z),
+			f(obj.Mass), f(obj.Radius),
+		})
+	}
+	return cw.w.Error()
+}
+
+func (cw *CSVWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type snapshotObject struct {
+	Name   string  `json:"name"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Z      float64 `json:"z"`
+	Vx     float64 `json:"vx"`
+	Vy     float64 `json:"vy"`
+	Vz     float64 `json:"vz"`
+	Mass   float64 `json:"mass"`
+	Radius float64 `json:"radius"`
+}
+
+type snapshot struct {
+	Time    float64          `json:"time"`
+	Objects []snapshotObject `json:"objects"`
+}
+
+/*
+One line per snapshot:
+{"time":0,"objects":[{"name":"","x":0,"y":0,"z":0,"vx":0,"vy":0,"vz":0,"mass":0,"radius":0}]}
+*/
+type JSONLWriter struct {
+	w *bufio.Writer
+	e *json.Encoder
+}
+
+func NewJSONLWriter(w io.Writer) SnapshotWriter {
+	bw := bufio.NewWriter(w)
+	return &JSONLWriter{w: bw, e: json.NewEncoder(bw)}
+}
+
+func (jw *JSONLWriter) WriteSnapshot(u *Universe) error {
+	s := snapshot{Time: u.Time, Objects: make([]snapshotObject, len(u.Objects))}
+	for i, obj := range u.Objects {
+		s.Objects[i] = snapshotObject{
+			obj.Name,
+			obj.Pos.X, obj.Pos.Y, obj.Pos.Z, obj.Vel.X, obj.Vel.Y, obj.Vel.Z,
+			obj.Mass, obj.Radius,
+		}
+	}
+	return jw.e.Encode(s)
+}
+
+func (jw *JSONLWriter) Close() error {
+	return jw.w.Flush()
+}
+
+const (
+	BINARY_SNAPSHOT_MAGIC   = "NBDY"
+	BINARY_SNAPSHOT_VERSION = 1
+)
+
+/*
+Little-endian binary format.
+
+	header   : "NBDY" uint16(version)
+	snapshot : float64(time) uint32(objects) object...
+	object   : uint16(len(name)) name float64(x y z vx vy vz mass radius)
+*/
+type BinaryWriter struct {
+	w      *bufio.Writer
+	header bool
+	buf    []byte
+}
+
+func NewBinaryWriter(w io.Writer) SnapshotWriter {
+	return &BinaryWriter{w: bufio.NewWriter(w)}
+}
+
+func (bw *BinaryWriter) WriteSnapshot(u *Universe) error {
+	b := bw.buf[:0]
+	if !bw.header {
+		bw.header = true
+		b = append(b, BINARY_SNAPSHOT_MAGIC...)
+		b = binary.LittleEndian.AppendUint16(b, BINARY_SNAPSHOT_VERSION)
+	}
+
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(u.Time))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(u.Objects)))
+	for _, obj := range u.Objects {
+		name := obj.Name
+		if len(name) > math.MaxUint16 {
+			name = name[:math.MaxUint16]
+		}
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(name)))
+		b = append(b, name...)
+		for _, v := range [...]float64{obj.Pos.X, obj.Pos.Y, obj.Pos.Z, obj.Vel.X, obj.Vel.Y, obj.Vel.Z, obj.Mass, obj.Radius} {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		}
+	}
+
+	bw.buf = b
+	_, err := bw.w.Write(b)
+	return err
+}
+
+func (bw *BinaryWriter) Close() error {
+	return bw.w.Flush()
+}
+
+/*
+Options of the trajectory output.
+Format is taken from the path extension when empty.
+*/
+type OutputOpt struct {
+	Path   string `json:"path,omitempty"`
+	Format string `json:"format,omitempty"`
+	Every  int    `json:"every,omitempty"`
+}
+
+// Writes a snapshot to a file once every N steps
+type Recorder struct {
+	Writer SnapshotWriter
+	Every  int
+	Path   string
+
+	file  *os.File
+	steps int
+}
+
+func NewRecorder(opt OutputOpt) (*Recorder, error) {
+	format := opt.Format
+	if format == "" {
+		format = SnapshotExtensions[filepath.Ext(opt.Path)]
+	}
+	if format == "" {
+		format = DEFAULT_SNAPSHOT_FORMAT
+	}
+	every := opt.Every
+	if every <= 0 {
+		every = 1
+	}
+
+	f, err := os.Create(opt.Path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewSnapshotWriter(format, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Recorder{Writer: w, Every: every, Path: opt.Path, file: f}, nil
+}
+
+/*
+Must be called after every step of the universe,
+the first call always writes
+*/
+func (r *Recorder) Record(u *Universe) error {
+	defer func() { r.steps++ }()
+	if r.steps%r.Every != 0 {
+		return nil
+	}
+	return r.Writer.WriteSnapshot(u)
+}
+
+// Can be called more than once
+func (r *Recorder) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.Writer.Close()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file = nil
+	return err
+}
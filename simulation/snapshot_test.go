@@ -0,0 +1,155 @@
+package simulation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	u := newTestUniverse(t, func(u *Universe) {
+		u.Dimensions = 3
+	},
+		newTestObject(Coordinates3D{1.5, -2, 3e-9}, Coordinates3D{0.1, 0.2, -0.3}, 5, 0.5),
+		newTestObject(Coordinates3D{40, 60, 80}, Coordinates3D{-1, 0, 1e10}, 1e-3, 2),
+	)
+	u.Objects[0].Name = `sun, "the" star`
+	u.Objects[1].Name = "ünïcode"
+
+	// Two snapshots at different times
+	var want []snapshot
+	record := func(w SnapshotWriter) {
+		want = want[:0]
+		u.Time = 0
+		for i := 0; i < 2; i++ {
+			if err := w.WriteSnapshot(u); err != nil {
+				t.Fatal(err)
+			}
+			s := snapshot{Time: u.Time}
+			for _, obj := range u.Objects {
+				s.Objects = append(s.Objects, snapshotObject{
+					obj.Name,
+					obj.Pos.X, obj.Pos.Y, obj.Pos.Z, obj.Vel.X, obj.Vel.Y, obj.Vel.Z,
+					obj.Mass, obj.Radius,
+				})
+			}
+			want = append(want, s)
+			u.Time += 0.125
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, format := range []string{"csv", "jsonl", "binary"} {
+		var buf bytes.Buffer
+		w, err := NewSnapshotWriter(format, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record(w)
+
+		var got []snapshot
+		switch format {
+		case "csv":
+			got = readTestCSV(t, &buf)
+		case "jsonl":
+			got = readTestJSONL(t, &buf)
+		case "binary":
+			got = readTestBinary(t, &buf)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: %d snapshots read, want %d", format, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].Time != want[i].Time || len(got[i].Objects) != len(want[i].Objects) {
+				t.Fatalf("%s: snapshot %d is %+v, want %+v", format, i, got[i], want[i])
+			}
+			for j := range want[i].Objects {
+				if got[i].Objects[j] != want[i].Objects[j] {
+					t.Errorf("%s: snapshot %d object %d is %+v, want %+v", format, i, j, got[i].Objects[j], want[i].Objects[j])
+				}
+			}
+		}
+	}
+}
+
+func readTestCSV(t *testing.T, r io.Reader) []snapshot {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := []string{"time", "name", "x", "y", "z", "vx", "vy", "vz", "mass", "radius"}
+	if len(rows) == 0 || len(rows[0]) != len(header) {
+		t.Fatalf("csv header is %v, want %v", rows, header)
+	}
+	for i, h := range header {
+		if rows[0][i] != h {
+			t.Fatalf("csv header is %v, want %v", rows[0], header)
+		}
+	}
+
+	var snaps []snapshot
+	for _, row := range rows[1:] {
+		var v [9]float64
+		for i, col := range []int{0, 2, 3, 4, 5, 6, 7, 8, 9} {
+			if v[i], err = strconv.ParseFloat(row[col], 64); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(snaps) == 0 || snaps[len(snaps)-1].Time != v[0] {
+			snaps = append(snaps, snapshot{Time: v[0]})
+		}
+		s := &snaps[len(snaps)-1]
+		s.Objects = append(s.Objects, snapshotObject{row[1], v[1], v[2], v[3], v[4], v[5], v[6], v[7], v[8]})
+	}
+	return snaps
+}
+
+func readTestJSONL(t *testing.T, r io.Reader) []snapshot {
+	var snaps []snapshot
+	d := json.NewDecoder(r)
+	for {
+		var s snapshot
+		if err := d.Decode(&s); err == io.EOF {
+			return snaps
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, s)
+	}
+}
+
+func readTestBinary(t *testing.T, r *bytes.Buffer) []snapshot {
+	magic := make([]byte, len(BINARY_SNAPSHOT_MAGIC))
+	var version uint16
+	if _, err := io.ReadFull(r, magic); err != nil {
+		t.Fatal(err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		t.Fatal(err)
+	}
+	if string(magic) != BINARY_SNAPSHOT_MAGIC || version != BINARY_SNAPSHOT_VERSION {
+		t.Fatalf("binary header is %q %d, want %q %d", magic, version, BINARY_SNAPSHOT_MAGIC, BINARY_SNAPSHOT_VERSION)
+	}
+
+	f := func() float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.Next(8)))
+	}
+	var snaps []snapshot
+	for r.Len() > 0 {
+		s := snapshot{Time: f()}
+		qtt := binary.LittleEndian.Uint32(r.Next(4))
+		for i := uint32(0); i < qtt; i++ {
+			name := string(r.Next(int(binary.LittleEndian.Uint16(r.Next(2)))))
+			s.Objects = append(s.Objects, snapshotObject{name, f(), f(), f(), f(), f(), f(), f(), f()})
+		}
+		snaps = append(snaps, s)
+	}
+	return snaps
+}
@@ -0,0 +1,113 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+const DEFAULT_SOFTENING_KERNEL = "plummer"
+
+/*
+Gravitational softening, removes the singularity of
+the gravity when two objects get too close.
+Length is the Plummer equivalent softening length (ε),
+0 disables the softening.
+*/
+type Softening struct {
+	Length float64 `json:"length,omitempty"`
+	Kernel string  `json:"kernel,omitempty"`
+}
+
+var SofteningKernels = []string{"plummer", "spline"}
+
+func (s *Softening) Init() error {
+	if s.Kernel == "" {
+		s.Kernel = DEFAULT_SOFTENING_KERNEL
+	}
+	if contains(SofteningKernels, s.Kernel) {
+		return nil
+	}
+	return fmt.Errorf("invalid softening kernel '%s'", s.Kernel)
+}
+
+/*
+Returns g(r) where the acceleration caused by a mass m is
+a = G*m*d*g(r)
+d is the vector between the objects;
+Without softening g(r) = 1/r^3.
+*/
+func (s Softening) ForceKernel(r float64) float64 {
+	eps := s.Length
+	if eps == 0 {
+		return 1 / (r * r * r)
+	}
+
+	if s.Kernel == "spline" {
+		// Cubic spline (Monaghan & Lattanzio), newtonian beyond h
+		h := 2.8 * eps
+		if r >= h {
+			return 1 / (r * r * r)
+		}
+		u := r / h
+		h3 := 1 / (h * h * h)
+		if u < 0.5 {
+			return h3 * (10.666666666667 + u*u*(32*u-38.4))
+		}
+		return h3 * (21.333333333333 - 48*u + 38.4*u*u - 10.666666666667*u*u*u - 0.066666666667/(u*u*u))
+	}
+
+	// Plummer
+	q := r*r + eps*eps
+	return 1 / (q * math.Sqrt(q))
+}
+
+/*
+Returns φ(r) where the potential caused by a mass m is
+Φ = -G*m*φ(r)
+Without softening φ(r) = 1/r.
+*/
+func (s Softening) PotentialKernel(r float64) float64 {
+	eps := s.Length
+	if eps == 0 {
+		return 1 / r
+	}
+
+	if s.Kernel == "spline" {
+		h := 2.8 * eps
+		if r >= h {
+			return 1 / r
+		}
+		u := r / h
+		if u < 0.5 {
+			return (2.8 - u*u*(5.333333333333+u*u*(6.4*u-9.6))) / h
+		}
+		return -(-3.2 + 0.066666666667/u + u*u*(10.666666666667+u*(-16+u*(9.6-2.133333333333*u)))) / h
+	}
+
+	// Plummer
+	return 1 / math.Sqrt(r*r+eps*eps)
+}
+
+/*
+Softened version of CalcGravitationalForce.
+F = G*m1*m2*r*g(r)
+*/
+func (s Softening) CalcGravitationalForce(m1, m2, r, k float64) float64 {
+	return k * m1 * m2 * r * s.ForceKernel(r)
+}
+
+/*
+Potential energy of a pair of objects.
+U = -G*m1*m2*φ(r)
+*/
+func (s Softening) CalcPotentialEnergy(m1, m2, r, k float64) float64 {
+	return -k * m1 * m2 * s.PotentialKernel(r)
+}
+
+/*
+Distance used by the time step criteria,
+never smaller than the softening length
+*/
+func (s Softening) GetDistance(r float64) float64 {
+	return math.Sqrt(r*r + s.Length*s.Length)
+}
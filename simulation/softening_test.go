@@ -0,0 +1,47 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSofteningKernels(t *testing.T) {
+	tests := []Softening{
+		{0, "plummer"},
+		{1, "plummer"},
+		{0.5, "plummer"},
+		{1, "spline"},
+		{0.5, "spline"},
+	}
+	for _, s := range tests {
+		// The force is minus the derivative of the potential
+		for r := 0.05; r < 5; r += 0.1 {
+			const h = 1e-5
+			want := (s.PotentialKernel(r-h) - s.PotentialKernel(r+h)) / (2 * h)
+			if got := r * s.ForceKernel(r); math.Abs(got-want) > 1e-5*math.Max(1, want) {
+				t.Errorf("%v: r*g(%v) = %v, want -dφ/dr = %v", s, r, got, want)
+				break
+			}
+		}
+
+		// Far away every kernel is newtonian
+		r := 100.0
+		if got := s.ForceKernel(r) * r * r * r; math.Abs(got-1) > 1e-3 {
+			t.Errorf("%v: g(%v)*r³ = %v, want 1", s, r, got)
+		}
+		if s.Length == 0 {
+			continue
+		}
+
+		// Plummer equivalent, the potential at the center is 1/ε for both kernels
+		if got := s.PotentialKernel(0); math.Abs(got*s.Length-1) > 1e-9 {
+			t.Errorf("%v: φ(0) = %v, want %v", s, got, 1/s.Length)
+		}
+		if s.Kernel == "spline" {
+			r := 2.8 * s.Length
+			if got := s.PotentialKernel(r); got != 1/r {
+				t.Errorf("%v: φ(%v) = %v, want newtonian %v", s, r, got, 1/r)
+			}
+		}
+	}
+}
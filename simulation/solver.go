@@ -0,0 +1,108 @@
+package simulation
+
+import "fmt"
+
+const (
+	DEFAULT_FORCE_SOLVER = "direct"
+	DEFAULT_THETA        = 0.5
+)
+
+/*
+Computes the gravitational accelerations of a universe.
+Only the Accel field of the given objects is changed,
+the sources of gravity are always all the universe objects.
+*/
+type ForceSolver interface {
+	ApplyGravity(u *Universe, objs []*Object)
+}
+
+var ForceSolvers = map[string]ForceSolver{
+	"direct":        DirectSum{},
+	"barnes-hut":    BarnesHut{},
+	"particle-mesh": ParticleMesh{},
+}
+
+var ForceSolverNames = []string{"direct", "barnes-hut", "particle-mesh"}
+
+func GetForceSolver(name string) (ForceSolver, error) {
+	if name == "" {
+		name = DEFAULT_FORCE_SOLVER
+	}
+	fs, ok := ForceSolvers[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid force solver '%s'", name)
+	}
+	return fs, nil
+}
+
+/*
+Sums the pull of every pair of objects,
+the nearest image on periodic universes.
+O(n^2)
+*/
+type DirectSum struct{}
+
+func (DirectSum) ApplyGravity(u *Universe, objs []*Object) {
+	period := u.GetPeriod()
+	u.ForEachObject(objs, func(obj *Object) {
+		if period == (Coordinates3D{}) {
+			obj.Accel = obj.GetResultingAcceleration(u.Objects, u.Gconst, u.Softening)
+			return
+		}
+		var accel Coordinates3D
+		for _, tar := range u.Objects {
+			accel = accel.Add(obj.GetImageAcceleration(tar, period, u.Gconst, u.Softening))
+		}
+		obj.Accel = accel
+	})
+}
+
+/*
+Approximates groups of distant objects by their center of mass
+using an octree. The universe Theta is the opening angle,
+smaller values are slower and closer to DirectSum.
+O(n log n)
+*/
+type BarnesHut struct{}
+
+func (BarnesHut) ApplyGravity(u *Universe, objs []*Object) {
+	tree := NewOctree(u.Objects)
+	period := u.GetPeriod()
+	u.ForEachObject(objs, func(obj *Object) {
+		obj.Accel = tree.GetAcceleration(obj, u.Theta, u.Gconst, u.Softening, period)
+	})
+}
+
+/*
+Deposits the mass on a grid, finds the potential with FFTs
+and interpolates the gradient back to the objects.
+The universe Mesh holds the grid options, the grid is always
+periodic on periodic universes. Objects closer than
+a couple of cells feel a weaker pull than the real one.
+Objects out of an isolated grid pull and are pulled
+by the direct sum.
+O(n + g log g + k*n), g being the amount of cells
+and k the objects out of the grid
+*/
+type ParticleMesh struct{}
+
+func (ParticleMesh) ApplyGravity(u *Universe, objs []*Object) {
+	m := NewMesh(u)
+	var inside, outside []*Object
+	for _, obj := range u.Objects {
+		if m.Contains(obj.Pos) {
+			inside = append(inside, obj)
+		} else {
+			outside = append(outside, obj)
+		}
+	}
+	m.Deposit(inside)
+	m.Solve(u)
+	u.ForEachObject(objs, func(obj *Object) {
+		if !m.Contains(obj.Pos) {
+			obj.Accel = obj.GetResultingAcceleration(u.Objects, u.Gconst, u.Softening)
+			return
+		}
+		obj.Accel = m.GetAcceleration(obj.Pos).Add(obj.GetResultingAcceleration(outside, u.Gconst, u.Softening))
+	})
+}
@@ -0,0 +1,90 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	STATE_VERSION      = 1
+	DEFAULT_STATE_PATH = "state.json"
+)
+
+// Everything needed to resume a simulation
+type State struct {
+	Version  int       `json:"version"`
+	Universe *Universe `json:"universe"`
+	RandOpt  RandOpt   `json:"random_options"`
+	EditOpt  EditOpt   `json:"edit_options"`
+}
+
+func WriteState(path string, st *State) error {
+	st.Version = STATE_VERSION
+	b, err := json.MarshalIndent(st, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+/*
+Reads a state file and initializes its universe, seeding
+it with the seed of the random options.
+Files from other versions are rejected.
+*/
+func ReadState(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	st := &State{}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("state '%s': %v", path, err)
+	}
+	if st.Version != STATE_VERSION {
+		return nil, fmt.Errorf("state '%s': unsupported version %d (expected %d)", path, st.Version, STATE_VERSION)
+	}
+	if st.Universe == nil {
+		return nil, fmt.Errorf("state '%s': missing universe", path)
+	}
+
+	if err := st.Universe.Init(); err != nil {
+		return nil, err
+	}
+	if err := st.RandOpt.Init(); err != nil {
+		return nil, err
+	}
+	st.Universe.SetSeed(st.RandOpt.Seed)
+	return st, nil
+}
+
+func (s *Simulation) GetStatePath() string {
+	if s.EditOpt.StatePath != "" {
+		return s.EditOpt.StatePath
+	}
+	return DEFAULT_STATE_PATH
+}
+
+func (s *Simulation) SaveState(path string) error {
+	return WriteState(path, &State{
+		Universe: s.Universe,
+		RandOpt:  s.RandOpt,
+		EditOpt:  s.EditOpt,
+	})
+}
+
+func (s *Simulation) LoadState(path string) error {
+	st, err := ReadState(path)
+	if err != nil {
+		return err
+	}
+	s.SetState(st)
+	return nil
+}
+
+func (s *Simulation) SetState(st *State) {
+	s.Universe = st.Universe
+	s.RandOpt = st.RandOpt
+	s.EditOpt = st.EditOpt
+}
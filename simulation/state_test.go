@@ -0,0 +1,62 @@
+package simulation
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// A saved universe keeps stepping exactly as the one it was saved from
+func TestStateRoundTrip(t *testing.T) {
+	tests := []struct {
+		integrator string
+		solver     string
+		timeStep   TimeStepOpt
+	}{
+		{"leapfrog", "direct", TimeStepOpt{}},
+		{"rk4", "barnes-hut", TimeStepOpt{}},
+		{"verlet", "direct", TimeStepOpt{Adaptive: true}},
+		{"leapfrog", "direct", TimeStepOpt{Adaptive: true, BlockSteps: true}},
+		{"leapfrog", "particle-mesh", TimeStepOpt{}},
+	}
+	for _, tt := range tests {
+		u := newTestUniverse(t, func(u *Universe) {
+			u.Dimensions = 3
+			u.IntegratorName = tt.integrator
+			u.SolverName = tt.solver
+			u.TimeStep = tt.timeStep
+			u.Dt = 0.5
+			u.Softening.Length = 1
+			u.Mesh.GridSize = 16
+			u.CollisionName = "elastic"
+		}, newTestCluster(100, 1)...)
+		for i := 0; i < 5; i++ {
+			u.Step()
+		}
+
+		path := filepath.Join(t.TempDir(), "state.json")
+		if err := WriteState(path, &State{Universe: u, RandOpt: RandOpt{Seed: 1}}); err != nil {
+			t.Fatal(err)
+		}
+		st, err := ReadState(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded := st.Universe
+
+		for i := 0; i < 10; i++ {
+			u.Step()
+			loaded.Step()
+		}
+		name := tt.integrator + " " + tt.solver
+		if loaded.Time != u.Time || len(loaded.Objects) != len(u.Objects) {
+			t.Fatalf("%s: loaded universe at t=%v with %d objects, want t=%v with %d", name, loaded.Time, len(loaded.Objects), u.Time, len(u.Objects))
+		}
+		for i, obj := range u.Objects {
+			l := loaded.Objects[i]
+			if l.Pos != obj.Pos || l.Vel != obj.Vel || l.Accel != obj.Accel || l.Mass != obj.Mass {
+				t.Errorf("%s: object %d is at %v %v, want %v %v", name, i, l.Pos, l.Vel, obj.Pos, obj.Vel)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	DEFAULT_TIME_STEP_CRITERION = "freefall"
+	DEFAULT_TIME_STEP_ETA       = 0.02
+	DEFAULT_TIME_STEP_MAX_LEVEL = 10
+)
+
+/*
+Options of the adaptive time step controller.
+The universe Dt is used as the largest step allowed.
+*/
+type TimeStepOpt struct {
+	Adaptive   bool    `json:"adaptive,omitempty"`
+	Criterion  string  `json:"criterion,omitempty"`
+	Eta        float64 `json:"eta,omitempty"`
+	MinDt      float64 `json:"min_dt,omitempty"`
+	BlockSteps bool    `json:"block_steps,omitempty"`
+	MaxLevel   int     `json:"max_level,omitempty"`
+}
+
+/*
+Returns the time step wanted by each object of the universe.
+eta is the accuracy parameter, smaller values give smaller steps.
+*/
+type TimeStepCriterion func(u *Universe, eta float64) []float64
+
+var TimeStepCriteria = map[string]TimeStepCriterion{
+	"freefall":     FreeFallTimeSteps,
+	"acceleration": AccelerationTimeSteps,
+}
+
+func (opt *TimeStepOpt) Init() error {
+	if opt.Criterion == "" {
+		opt.Criterion = DEFAULT_TIME_STEP_CRITERION
+	}
+	if _, ok := TimeStepCriteria[opt.Criterion]; !ok {
+		return fmt.Errorf("invalid time step criterion '%s'", opt.Criterion)
+	}
+	if opt.Eta == 0 {
+		opt.Eta = DEFAULT_TIME_STEP_ETA
+	}
+	if opt.MaxLevel == 0 {
+		opt.MaxLevel = DEFAULT_TIME_STEP_MAX_LEVEL
+	}
+	return nil
+}
+
+/*
+Using the free-fall time of every pair of objects.
+dt = eta * √(r^3 / (G*(m1+m2)))
+r is softened so coincident objects don't stop the time.
+*/
+func FreeFallTimeSteps(u *Universe, eta float64) []float64 {
+	dts := make([]float64, len(u.Objects))
+	for i, obj := range u.Objects {
+		dts[i] = math.Inf(1)
+		for _, tar := range u.Objects {
+			if tar == obj {
+				continue
+			}
+			r := u.Softening.GetDistance(u.GetDistance(obj, tar))
+			t := eta * math.Sqrt(r*r*r/(u.Gconst*(obj.Mass+tar.Mass)))
+			if t < dts[i] {
+				dts[i] = t
+			}
+		}
+	}
+	return dts
+}
+
+/*
+Using the time the object takes to cross its own radius
+(or the softening length when bigger) under its current acceleration.
+dt = eta * √(radius / |a|)
+*/
+func AccelerationTimeSteps(u *Universe, eta float64) []float64 {
+	u.ApplyGravity()
+	dts := make([]float64, len(u.Objects))
+	for i, obj := range u.Objects {
+		a := obj.Accel.Len()
+		if a == 0 {
+			dts[i] = math.Inf(1)
+			continue
+		}
+		dts[i] = eta * math.Sqrt(math.Max(obj.Radius, u.Softening.Length)/a)
+	}
+	return dts
+}
+
+func (u *Universe) GetMinDt() float64 {
+	if u.TimeStep.MinDt > 0 {
+		return u.TimeStep.MinDt
+	}
+	return u.Dt / math.Pow(2, float64(u.TimeStep.MaxLevel))
+}
+
+func (u *Universe) getTimeSteps() []float64 {
+	criterion, ok := TimeStepCriteria[u.TimeStep.Criterion]
+	if !ok {
+		criterion = TimeStepCriteria[DEFAULT_TIME_STEP_CRITERION]
+	}
+	dts := criterion(u, u.TimeStep.Eta)
+
+	minDt := u.GetMinDt()
+	for i := range dts {
+		if dts[i] < minDt || math.IsNaN(dts[i]) {
+			dts[i] = minDt
+		}
+	}
+	return dts
+}
+
+/*
+Advances every object by the smallest time step wanted
+by any of them, limited to [MinDt, Dt].
+Returns the time step used.
+*/
+func (u *Universe) AdaptiveStep() float64 {
+	dt := u.Dt
+	for _, t := range u.getTimeSteps() {
+		if t < dt {
+			dt = t
+		}
+	}
+	u.Integrator.Step(u, dt)
+	return dt
+}
+
+/*
+Advances the universe by Dt giving each object its own
+power of two subdivision of Dt (block time steps).
+Objects are integrated with a kick-drift-kick leapfrog,
+only kicking the objects whose step begins or ends on
+the current substep, so the selected integrator is not used.
+Returns the smallest substep used.
+*/
+func (u *Universe) BlockStep() float64 {
+	dts := u.getTimeSteps()
+
+	levels := make([]int, len(u.Objects))
+	var maxLevel int
+	for i, t := range dts {
+		levels[i] = u.getBlockLevel(t)
+		if levels[i] > maxLevel {
+			maxLevel = levels[i]
+		}
+	}
+
+	// The object i is kicked every strides[i] substeps
+	nsub := 1 << maxLevel
+	h := u.Dt / float64(nsub)
+	strides := make([]int, len(u.Objects))
+	for i, l := range levels {
+		strides[i] = 1 << (maxLevel - l)
+	}
+
+	u.ApplyGravity()
+	active := make([]*Object, 0, len(u.Objects))
+	for s := 0; s < nsub; s++ {
+		for i, obj := range u.Objects {
+			if s%strides[i] == 0 {
+				obj.Vel = obj.Vel.Add(obj.Accel.Scale(h * float64(strides[i]) / 2))
+			}
+		}
+
+		for _, obj := range u.Objects {
+			obj.Pos = obj.Pos.Add(obj.Vel.Scale(h))
+		}
+
+		active = active[:0]
+		for i, obj := range u.Objects {
+			if (s+1)%strides[i] == 0 {
+				active = append(active, obj)
+			}
+		}
+		u.ApplyGravityTo(active)
+
+		for i, obj := range u.Objects {
+			if (s+1)%strides[i] == 0 {
+				obj.Vel = obj.Vel.Add(obj.Accel.Scale(h * float64(strides[i]) / 2))
+			}
+		}
+	}
+	return h
+}
+
+/*
+Returns the smallest level k where Dt/2^k <= dt,
+limited to [0, MaxLevel]
+*/
+func (u *Universe) getBlockLevel(dt float64) int {
+	k := 0
+	for k < u.TimeStep.MaxLevel && u.Dt/float64(int(1)<<k) > dt {
+		k++
+	}
+	return k
+}
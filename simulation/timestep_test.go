@@ -0,0 +1,83 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAdaptiveTimeStepEccentricOrbit(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(u *Universe)
+		energy float64 // Highest relative energy drift
+	}{
+		// The fixed step can't follow the pericenter passage
+		{"fixed", func(u *Universe) {}, math.Inf(1)},
+		{"freefall", func(u *Universe) {
+			u.TimeStep.Adaptive = true
+		}, 1e-2},
+		{"acceleration", func(u *Universe) {
+			u.TimeStep.Adaptive = true
+			u.TimeStep.Criterion = "acceleration"
+		}, 1e-2},
+		{"block", func(u *Universe) {
+			u.TimeStep.Adaptive = true
+			u.TimeStep.BlockSteps = true
+		}, 1e-2},
+	}
+	for _, tt := range tests {
+		u := newTestUniverse(t, func(u *Universe) {
+			u.IntegratorName = "leapfrog"
+			tt.setup(u)
+		}, newTestEccentricBinary(10, 0.9)...)
+		ref := u.GetDiagnostics().Energy
+
+		// Two periods of 2π√(a³/(G*2m))
+		var drift float64
+		for u.Time < 4*math.Pi*math.Sqrt(1000/2.0) {
+			u.Step()
+			drift = math.Max(drift, math.Abs((u.GetDiagnostics().Energy-ref)/ref))
+		}
+		if tt.name == "fixed" && drift < 0.1 {
+			t.Fatalf("fixed: energy drifts only %g, the orbit doesn't need adaptive steps", drift)
+		}
+		if drift > tt.energy {
+			t.Errorf("%s: energy drifts %g, want at most %g", tt.name, drift, tt.energy)
+		}
+	}
+}
+
+func TestGetBlockLevel(t *testing.T) {
+	tests := []struct {
+		dt   float64
+		want int
+	}{
+		{2, 0},
+		{1, 0},
+		{0.5, 1},
+		{0.3, 2},
+		{0.25, 2},
+		{1e-9, 10},
+	}
+	u := newTestUniverse(t, nil)
+	for _, tt := range tests {
+		if got := u.getBlockLevel(tt.dt); got != tt.want {
+			t.Errorf("getBlockLevel(%v) = %v, want %v", tt.dt, got, tt.want)
+		}
+	}
+}
+
+/*
+Returns two unit masses on an orbit of semi-major axis a and
+eccentricity e, starting at the apocenter
+*/
+func newTestEccentricBinary(a, e float64) []*Object {
+	r := a * (1 + e)
+	// Relative speed at the apocenter with G = 1 and a total mass of 2
+	v := math.Sqrt(2 * (1 - e) / r)
+	center := Coordinates3D{X: TEST_UNIVERSE_SIZE / 2, Y: TEST_UNIVERSE_SIZE / 2, Z: TEST_UNIVERSE_SIZE / 2}
+	return []*Object{
+		newTestObject(center.Add(Coordinates3D{X: -r / 2}), Coordinates3D{Y: -v / 2}, 1, 0.01),
+		newTestObject(center.Add(Coordinates3D{X: r / 2}), Coordinates3D{Y: v / 2}, 1, 0.01),
+	}
+}
@@ -0,0 +1,99 @@
+package simulation
+
+const (
+	DEFAULT_TRAIL_LENGTH = 200
+	DEFAULT_TRAIL_EVERY  = 2
+)
+
+// Ring buffer with the last positions of an object
+type Trail struct {
+	points []Coordinates3D
+	start  int
+	n      int
+}
+
+func NewTrail(length int) *Trail {
+	return &Trail{points: make([]Coordinates3D, length)}
+}
+
+// Adds a position, overwriting the oldest one when full
+func (t *Trail) Push(p Coordinates3D) {
+	if len(t.points) == 0 {
+		return
+	}
+	t.points[(t.start+t.n)%len(t.points)] = p
+	if t.n < len(t.points) {
+		t.n++
+	} else {
+		t.start = (t.start + 1) % len(t.points)
+	}
+}
+
+func (t *Trail) Len() int {
+	return t.n
+}
+
+func (t *Trail) Cap() int {
+	return len(t.points)
+}
+
+// Returns the i-th position, 0 is the oldest
+func (t *Trail) At(i int) Coordinates3D {
+	return t.points[(t.start+i)%len(t.points)]
+}
+
+// Returns the i-th position counting from the newest one
+func (t *Trail) FromEnd(i int) Coordinates3D {
+	return t.At(t.n - 1 - i)
+}
+
+// Changes the capacity keeping the newest positions
+func (t *Trail) Resize(length int) {
+	nt := NewTrail(length)
+	for i := 0; i < t.n; i++ {
+		nt.Push(t.At(i))
+	}
+	*t = *nt
+}
+
+func (t *Trail) Clear() {
+	t.start, t.n = 0, 0
+}
+
+/*
+Adds the current position of every object to its trail,
+and the center of mass to the trail of the universe.
+All trails are sampled together, so the i-th position from
+the end of every trail was taken at the same time.
+*/
+func (u *Universe) RecordTrails(length int) {
+	if length <= 0 {
+		length = DEFAULT_TRAIL_LENGTH
+	}
+	push := func(t **Trail, p Coordinates3D) {
+		if *t == nil {
+			*t = NewTrail(length)
+		} else if (*t).Cap() != length {
+			(*t).Resize(length)
+		}
+		(*t).Push(p)
+	}
+
+	for _, obj := range u.Objects {
+		push(&obj.Trail, obj.Pos)
+	}
+	com, _ := u.GetCenterOfMass()
+	push(&u.CenterOfMassTrail, com)
+}
+
+// Forgets the positions of every trail
+func (u *Universe) ClearTrails() {
+	for _, obj := range u.Objects {
+		if obj.Trail != nil {
+			obj.Trail.Clear()
+		}
+	}
+	if u.CenterOfMassTrail != nil {
+		u.CenterOfMassTrail.Clear()
+	}
+}
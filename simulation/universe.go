@@ -1,59 +1,317 @@
 package simulation
 
 import (
+	"fmt"
 	"image/color"
 	"math"
+	"math/rand"
+	"runtime"
+)
 
-	"github.com/Guilherme-De-Marchi/nbody-go/util"
+const (
+	DEFAULT_DT         = 1
+	DEFAULT_DIMENSIONS = 2
 )
 
 type Universe struct {
-	Size    Coordinates2D `json:"size,omitempty"`
-	Gconst  float64       `json:"gravitational_const,omitempty"`
-	Objects []*Object
+	Dimensions     int           `json:"dimensions,omitempty"` // 2 or 3
+	Size           Coordinates3D `json:"size,omitempty"`
+	Gconst         float64       `json:"gravitational_const,omitempty"`
+	Dt             float64       `json:"dt,omitempty"`
+	Time           float64       `json:"time,omitempty"`
+	IntegratorName string        `json:"integrator,omitempty"`
+	TimeStep       TimeStepOpt   `json:"time_step,omitempty"`
+	SolverName     string        `json:"force_solver,omitempty"`
+	Theta          float64       `json:"opening_angle,omitempty"`
+	Parallel       bool          `json:"parallel,omitempty"`
+	Workers        int           `json:"workers,omitempty"`
+	Softening      Softening     `json:"softening,omitempty"`
+	Mesh           MeshOpt       `json:"mesh,omitempty"`
+	CollisionName  string        `json:"collision,omitempty"`
+	Restitution    float64       `json:"restitution,omitempty"`
+	BoundaryName   string        `json:"boundary,omitempty"`
+	Objects        []*Object     `json:"objects,omitempty"`
+
+	Integrator  Integrator        `json:"-"`
+	ForceSolver ForceSolver       `json:"-"`
+	Collision   CollisionPolicy   `json:"-"`
+	Boundary    BoundaryCondition `json:"-"`
+	StepDt      float64           `json:"-"` // Time step used on the last step
+	Collisions  int               `json:"-"` // Amount of collisions resolved
+	Absorbed    int               `json:"-"` // Amount of objects removed by the boundary
+	Reference   *Diagnostics      `json:"-"` // Initial values used to measure the drift
+	Rand        *rand.Rand        `json:"-"` // Source of every random value of the universe
+	Seed        int64             `json:"-"` // Seed of Rand
+
+	CenterOfMassTrail *Trail `json:"-"` // Sampled along the trails of the objects
+
+	meshKernel *meshKernel
+	meshGrid   []complex128
+	quiet      bool // Set on clones so they don't log nor count events
 }
 
-func NewUniverse(size Coordinates2D, gConst float64, objs ...*Object) *Universe {
+func NewUniverse(size Coordinates3D, gConst float64, objs ...*Object) *Universe {
 	return &Universe{
-		Size:    size,
-		Gconst:  gConst,
-		Objects: objs,
+		Dimensions:     DEFAULT_DIMENSIONS,
+		Size:           size,
+		Gconst:         gConst,
+		Dt:             DEFAULT_DT,
+		IntegratorName: DEFAULT_INTEGRATOR,
+		TimeStep: TimeStepOpt{
+			Criterion: DEFAULT_TIME_STEP_CRITERION,
+			Eta:       DEFAULT_TIME_STEP_ETA,
+			MaxLevel:  DEFAULT_TIME_STEP_MAX_LEVEL,
+		},
+		SolverName:    DEFAULT_FORCE_SOLVER,
+		Theta:         DEFAULT_THETA,
+		Workers:       runtime.GOMAXPROCS(0),
+		Softening:     Softening{Kernel: DEFAULT_SOFTENING_KERNEL},
+		Mesh:          MeshOpt{DEFAULT_MESH_GRID_SIZE, DEFAULT_MESH_ASSIGNMENT, DEFAULT_MESH_BOUNDARY},
+		CollisionName: DEFAULT_COLLISION,
+		BoundaryName:  DEFAULT_BOUNDARY,
+		Objects:       objs,
+		Integrator:    Integrators[DEFAULT_INTEGRATOR],
+		ForceSolver:   ForceSolvers[DEFAULT_FORCE_SOLVER],
+		Collision:     CollisionPolicies[DEFAULT_COLLISION],
 	}
 }
 
-func NewRandomUniverse(size Coordinates2D, gConst float64, massR, radR [2]float64, qtt int) *Universe {
-	objs := GetRandomObjects(
+func NewRandomUniverse(size Coordinates3D, gConst float64, massR, radR [2]float64, qtt int, seed int64) *Universe {
+	u := NewUniverse(size, gConst)
+	u.SetSeed(seed)
+	u.AddObjects(GetRandomObjects(
+		u.Rand,
 		size,
 		massR,
 		radR,
 		qtt,
-	)
-	return NewUniverse(size, gConst, objs...)
+	)...)
+	return u
+}
+
+/*
+Fills the unset fields with their default values
+and resolves the components selected by name.
+The objects of 2D universes are kept on the z = 0 plane.
+*/
+func (u *Universe) Init() error {
+	switch u.Dimensions {
+	case 0:
+		u.Dimensions = DEFAULT_DIMENSIONS
+	case 2, 3:
+	default:
+		return fmt.Errorf("invalid amount of dimensions %d, must be 2 or 3", u.Dimensions)
+	}
+	if u.Dimensions == 3 && u.Size.Z == 0 {
+		u.Size.Z = u.Size.X
+	}
+	if u.Dimensions == 2 {
+		u.Size.Z = 0
+		for _, obj := range u.Objects {
+			obj.Pos.Z, obj.Vel.Z, obj.Accel.Z = 0, 0, 0
+		}
+	}
+
+	if u.Gconst == 0 {
+		u.Gconst = G
+	}
+	if u.Dt == 0 {
+		u.Dt = DEFAULT_DT
+	}
+	if u.Theta == 0 {
+		u.Theta = DEFAULT_THETA
+	}
+	if u.Workers == 0 {
+		u.Workers = runtime.GOMAXPROCS(0)
+	}
+	if u.Restitution == 0 {
+		u.Restitution = DEFAULT_RESTITUTION
+	}
+	u.Restitution = math.Max(0, math.Min(1, u.Restitution))
+	if err := u.TimeStep.Init(); err != nil {
+		return err
+	}
+	if err := u.Softening.Init(); err != nil {
+		return err
+	}
+	if err := u.Mesh.Init(); err != nil {
+		return err
+	}
+	if err := u.SetForceSolver(u.SolverName); err != nil {
+		return err
+	}
+	if err := u.SetCollision(u.CollisionName); err != nil {
+		return err
+	}
+	if err := u.SetBoundary(u.BoundaryName); err != nil {
+		return err
+	}
+	return u.SetIntegrator(u.IntegratorName)
 }
 
 func (u *Universe) AddObjects(obj ...*Object) {
 	u.Objects = append(u.Objects, obj...)
+	u.ResetDiagnostics()
 }
 
-func (u *Universe) ApplyGravity() {
+// Removes every object for which f returns true
+func (u *Universe) RemoveObjects(f func(obj *Object) bool) {
+	objs := u.Objects[:0]
 	for _, obj := range u.Objects {
-		for _, tar := range u.Objects {
-			if tar == obj {
-				continue
-			}
-			f := obj.GetGravitationalForce(tar, u.Gconst)
-			// log.Println("resulting force:", f, "\n")
-			obj.ApplyForce(f, tar)
+		if !f(obj) {
+			objs = append(objs, obj)
+		}
+	}
+	for i := len(objs); i < len(u.Objects); i++ {
+		u.Objects[i] = nil
+	}
+	u.Objects = objs
+}
+
+// Replaces the objects of the universe and restarts its clock
+func (u *Universe) Reset(objs ...*Object) {
+	u.Objects = objs
+	u.Time = 0
+	u.Collisions = 0
+	u.Absorbed = 0
+	u.ResetDiagnostics()
+	u.CenterOfMassTrail = nil
+}
+
+// Returns obj when it's on the universe, nil otherwise
+func (u *Universe) Find(obj *Object) *Object {
+	if obj == nil {
+		return nil
+	}
+	for _, o := range u.Objects {
+		if o == obj {
+			return o
+		}
+	}
+	return nil
+}
+
+// Returns the object that comes after obj, the first one when obj isn't found
+func (u *Universe) GetNextObject(obj *Object) *Object {
+	if len(u.Objects) == 0 {
+		return nil
+	}
+	for i, o := range u.Objects {
+		if o == obj {
+			return u.Objects[(i+1)%len(u.Objects)]
+		}
+	}
+	return u.Objects[0]
+}
+
+// Returns the closest object to obj and the distance between their centers
+func (u *Universe) GetNearest(obj *Object) (*Object, float64) {
+	var nearest *Object
+	best := math.Inf(1)
+	for _, o := range u.Objects {
+		if o == obj {
+			continue
+		}
+		if d := u.GetDistance(obj, o); d < best {
+			nearest, best = o, d
 		}
 	}
+	return nearest, best
+}
+
+func (u *Universe) SetIntegrator(name string) error {
+	in, err := GetIntegrator(name)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = DEFAULT_INTEGRATOR
+	}
+	u.IntegratorName = name
+	u.Integrator = in
+	return nil
+}
+
+func (u *Universe) SetForceSolver(name string) error {
+	fs, err := GetForceSolver(name)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = DEFAULT_FORCE_SOLVER
+	}
+	u.SolverName = name
+	u.ForceSolver = fs
+	return nil
+}
+
+func (u *Universe) SetCollision(name string) error {
+	cp, err := GetCollisionPolicy(name)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = DEFAULT_COLLISION
+	}
+	u.CollisionName = name
+	u.Collision = cp
+	return nil
+}
+
+/*
+Sets the acceleration of every object from the current positions.
+No object is moved.
+*/
+func (u *Universe) ApplyGravity() {
+	u.ApplyGravityTo(u.Objects)
+}
+
+// Same as ApplyGravity but only for the given objects
+func (u *Universe) ApplyGravityTo(objs []*Object) {
+	if u.ForceSolver == nil {
+		if err := u.SetForceSolver(u.SolverName); err != nil {
+			u.SetForceSolver(DEFAULT_FORCE_SOLVER)
+		}
+	}
+	u.ForceSolver.ApplyGravity(u, objs)
+}
+
+/*
+Advances the universe using the selected integrator, applies the
+boundary, resolves the collisions and accumulates the simulated time.
+With adaptive time steps Dt is the largest step taken.
+*/
+func (u *Universe) Step() {
+	if u.Integrator == nil {
+		if err := u.SetIntegrator(u.IntegratorName); err != nil {
+			u.SetIntegrator(DEFAULT_INTEGRATOR)
+		}
+	}
+
+	switch {
+	case u.TimeStep.Adaptive && u.TimeStep.BlockSteps:
+		u.StepDt = u.BlockStep()
+		u.Time += u.Dt
+	case u.TimeStep.Adaptive:
+		u.StepDt = u.AdaptiveStep()
+		u.Time += u.StepDt
+	default:
+		u.Integrator.Step(u, u.Dt)
+		u.StepDt = u.Dt
+		u.Time += u.Dt
+	}
+
+	u.HandleBoundary()
+	u.HandleCollisions()
 }
 
 // Fix this
 /*
-Returns the gradient matrix and the highest value found
+Returns the gradient matrix and the highest value found.
+toPos gives the point of the universe under each pixel.
 */
-func (u *Universe) GetViewGravityGradient(exp float64, size, r, offset [2]float64) ([][]float64, float64) {
-	obj := NewObject("-", color.RGBA{}, Coordinates2D{}, 10000, 0) // irrelevant object
+func (u *Universe) GetViewGravityGradient(exp float64, size [2]float64, toPos func(px, py float64) Coordinates3D) ([][]float64, float64) {
+	obj := NewObject("-", color.RGBA{}, Coordinates3D{}, 10000, 0) // irrelevant object
 	var totalf float64
 
 	gradient := make([][]float64, int(size[1]))
@@ -62,9 +320,9 @@ func (u *Universe) GetViewGravityGradient(exp float64, size, r, offset [2]float6
 		gradient[i] = make([]float64, int(size[0]))
 		for j := range gradient[i] {
 			totalf = 0
-			obj.Pos.X, obj.Pos.Y = util.PxToPos([2]float64{float64(j), float64(i)}, r, offset)
+			obj.Pos = toPos(float64(j), float64(i))
 			for _, tar := range u.Objects {
-				totalf += obj.GetGravitationalForce(tar, u.Gconst).Magnitude
+				totalf += obj.GetGravitationalForce(tar, u.Gconst, u.Softening).Magnitude
 			}
 			gradient[i][j] = math.Pow(totalf, exp)
 
@@ -78,7 +336,7 @@ func (u *Universe) GetViewGravityGradient(exp float64, size, r, offset [2]float6
 }
 
 func (u *Universe) GetTotalGravityGradient(step, exp float64) ([][]float64, float64) {
-	obj := NewObject("-", color.RGBA{}, Coordinates2D{}, 1, 0) // irrelevant object
+	obj := NewObject("-", color.RGBA{}, Coordinates3D{}, 1, 0) // irrelevant object
 	var totalf float64
 
 	tX := int(u.Size.X / step)
@@ -90,7 +348,7 @@ func (u *Universe) GetTotalGravityGradient(step, exp float64) ([][]float64, floa
 		for j := 0; j < tX; j++ {
 			totalf = 0
 			for _, tar := range u.Objects {
-				totalf += obj.GetGravitationalForce(tar, u.Gconst).Magnitude
+				totalf += obj.GetGravitationalForce(tar, u.Gconst, u.Softening).Magnitude
 			}
 			gradient[i][j] = math.Pow(totalf, exp)
 
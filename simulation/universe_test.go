@@ -0,0 +1,43 @@
+package simulation
+
+import "testing"
+
+func TestStepAdvancesClock(t *testing.T) {
+	for _, dt := range []float64{0.5, 1, 3} {
+		u := newTestUniverse(t, func(u *Universe) {
+			u.Dt = dt
+		}, newTestBinary(10, 1000)...)
+		for i := 0; i < 10; i++ {
+			u.Step()
+		}
+		if u.Time != 10*dt {
+			t.Errorf("dt %v: time is %v after 10 steps, want %v", dt, u.Time, 10*dt)
+		}
+
+		u.Reset(newTestBinary(10, 1000)...)
+		if u.Time != 0 {
+			t.Errorf("dt %v: time is %v after a reset, want 0", dt, u.Time)
+		}
+	}
+}
+
+func TestTimeStepConvergence(t *testing.T) {
+	// Distance from the start after a period, halving dt must cut it by about 4 on leapfrog
+	var errs []float64
+	for _, dt := range []float64{4, 2, 1} {
+		u := newTestUniverse(t, func(u *Universe) {
+			u.Dt = dt
+			u.IntegratorName = "leapfrog"
+		}, newTestBinary(10, 1000)...)
+		start := u.Objects[1].Pos
+		for u.Time < 1000 {
+			u.Step()
+		}
+		errs = append(errs, u.Objects[1].Pos.Sub(start).Len())
+	}
+	for i := 1; i < len(errs); i++ {
+		if ratio := errs[i-1] / errs[i]; ratio < 3.5 || ratio > 4.5 {
+			t.Errorf("halving dt cut the error by %v, want about 4 (errors %v)", ratio, errs)
+		}
+	}
+}
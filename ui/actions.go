@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"log"
+
 	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -20,12 +22,30 @@ var KeyMap = map[ebiten.Key]ActionFunc{
 	ebiten.Key3:      ShowObjectName,
 	ebiten.Key4:      ShowWinGravityGrad,
 	ebiten.Key5:      ShowTotalGravityGrad,
+	ebiten.Key6:      ShowPlots,
+	ebiten.Key7:      ShowTrails,
+	ebiten.KeyTab:    SelectNextObject,
 
 	ebiten.KeyZ: SetZoom,
 	ebiten.KeyR: NewRandomUniverse,
 	ebiten.KeyG: SetGconst,
 	ebiten.KeyO: SetObjects,
 	ebiten.KeyE: SetGradExp,
+	ebiten.KeyI: SwitchIntegrator,
+	ebiten.KeyT: SetDt,
+	ebiten.KeyB: SwitchForceSolver,
+	ebiten.KeyX: SwitchCollision,
+	ebiten.KeyK: SwitchBoundary,
+	ebiten.KeyM: SetSelectedMass,
+	ebiten.KeyN: SetSelectedRadius,
+	ebiten.KeyC: SwitchCameraMode,
+	ebiten.KeyF: SwitchTrailFrame,
+	ebiten.KeyP: SetPlaceObject,
+	ebiten.KeyV: SwitchProjection,
+
+	ebiten.KeyF5: QuickSave,
+	ebiten.KeyF8: ToggleRecording,
+	ebiten.KeyF9: QuickLoad,
 }
 
 // Key: W : Offset.Y -= Offset desloc
@@ -90,29 +110,57 @@ func ShowTotalGravityGrad(g *Game) {
 	}
 }
 
+// Key: 6 : Show plots (on/off)
+func ShowPlots(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.Key6) {
+		g.EditOpt.ShowPlots = !g.EditOpt.ShowPlots
+	}
+}
+
+// Key: 7 : Show trails (on/off), they start empty
+func ShowTrails(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.Key7) {
+		g.EditOpt.ShowTrails = !g.EditOpt.ShowTrails
+		if g.EditOpt.ShowTrails {
+			g.Universe.ClearTrails()
+		}
+	}
+}
+
+// Key: Tab : Selects the next object
+func SelectNextObject(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.Select(g.Universe.GetNextObject((*simul.Simulation)(g).GetSelected()))
+	}
+}
+
 /*
 Keys:
 
-	Z + ArrowUp : Zoom /= Zoom desloc.
-	Z + ArrowDown : Zoom *= Zoom desloc.
+	Z + ArrowUp : Zoom /= Zoom desloc, around the center of the screen.
+	Z + ArrowDown : Zoom *= Zoom desloc, around the center of the screen.
 */
 func SetZoom(g *Game) {
 	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
-		g.EditOpt.Zoom /= g.EditOpt.ZoomDesloc
+		g.ZoomAt(SCREEN_WIDTH/2, SCREEN_HEIGHT/2, 1/g.EditOpt.ZoomDesloc)
 	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
-		g.EditOpt.Zoom *= g.EditOpt.ZoomDesloc
+		g.ZoomAt(SCREEN_WIDTH/2, SCREEN_HEIGHT/2, g.EditOpt.ZoomDesloc)
 	}
 }
 
-// Key: R : Generates e new random universe
+/*
+Key: R : Generates e new random universe with a new seed, following
+the model of the generation type. The seed is logged so the
+universe can be generated again
+*/
 func NewRandomUniverse(g *Game) {
-	g.Universe = simul.NewRandomUniverse(
-		g.Universe.Size,
-		g.Universe.Gconst,
-		g.RandOpt.MassR,
-		g.RandOpt.RadR,
-		g.RandOpt.ObjectQtt,
-	)
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		seed := simul.NewSeed()
+		log.Println("[GAME] NEW RANDOM UNIVERSE, SEED:", seed)
+		if err := (*simul.Simulation)(g).Regenerate(seed); err != nil {
+			log.Println("[GAME ERROR]:", err)
+		}
+	}
 }
 
 /*
@@ -124,8 +172,24 @@ Keys:
 func SetGconst(g *Game) {
 	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
 		g.Universe.Gconst *= g.EditOpt.GconstDesloc
+		g.Universe.ResetDiagnostics()
 	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
 		g.Universe.Gconst /= g.EditOpt.GconstDesloc
+		g.Universe.ResetDiagnostics()
+	}
+}
+
+/*
+Keys:
+
+	T + ArrowUp : Time step *= dt desloc.
+	T + ArrowDown : Time step /= dt desloc.
+*/
+func SetDt(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		g.Universe.Dt *= g.EditOpt.DtDesloc
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		g.Universe.Dt /= g.EditOpt.DtDesloc
 	}
 }
 
@@ -139,13 +203,7 @@ Keys:
 */
 func SetObjects(g *Game) {
 	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
-		objs := simul.GetRandomObjects(
-			g.Universe.Size,
-			g.RandOpt.MassR,
-			g.RandOpt.RadR,
-			g.EditOpt.ObjectsDesloc,
-		)
-		g.Universe.AddObjects(objs...)
+		g.Universe.AddRandomObjects(g.RandOpt, g.EditOpt.ObjectsDesloc)
 	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
 		if len(g.Universe.Objects) == 0 {
 			return
@@ -156,6 +214,7 @@ func SetObjects(g *Game) {
 		} else {
 			g.Universe.Objects = g.Universe.Objects[:r]
 		}
+		g.Universe.ResetDiagnostics()
 	}
 }
 
@@ -172,3 +231,151 @@ func SetGradExp(g *Game) {
 		g.EditOpt.GradExp -= g.EditOpt.GradExpDesloc
 	}
 }
+
+// Key: I : Switches to the next integrator
+func SwitchIntegrator(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		g.Universe.SetIntegrator(simul.NextName(simul.IntegratorNames, g.Universe.IntegratorName))
+		log.Println("[GAME] INTEGRATOR:", g.Universe.IntegratorName)
+	}
+}
+
+// Key: B : Switches to the next force solver
+func SwitchForceSolver(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		g.Universe.SetForceSolver(simul.NextName(simul.ForceSolverNames, g.Universe.SolverName))
+		log.Println("[GAME] FORCE SOLVER:", g.Universe.SolverName)
+	}
+}
+
+// Key: X : Switches to the next collision policy
+func SwitchCollision(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		g.Universe.SetCollision(simul.NextName(simul.CollisionNames, g.Universe.CollisionName))
+		log.Println("[GAME] COLLISION:", g.Universe.CollisionName)
+	}
+}
+
+// Key: K : Switches to the next boundary condition
+func SwitchBoundary(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) {
+		g.Universe.SetBoundary(simul.NextName(simul.BoundaryNames, g.Universe.BoundaryName))
+		log.Println("[GAME] BOUNDARY:", g.Universe.BoundaryName)
+	}
+}
+
+// Key: F8 : Starts/stops recording the trajectory to the output path
+func ToggleRecording(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF8) {
+		if g.Recorder == nil {
+			g.StartRecording()
+		} else {
+			g.StopRecording()
+		}
+	}
+}
+
+// Key: F5 : Saves the simulation state to the state path
+func QuickSave(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		path := (*simul.Simulation)(g).GetStatePath()
+		if err := (*simul.Simulation)(g).SaveState(path); err != nil {
+			log.Println("[GAME ERROR]:", err)
+			return
+		}
+		log.Println("[GAME] STATE SAVED TO", path)
+	}
+}
+
+// Key: F9 : Loads the simulation state from the state path
+func QuickLoad(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+		path := (*simul.Simulation)(g).GetStatePath()
+		if err := (*simul.Simulation)(g).LoadState(path); err != nil {
+			log.Println("[GAME ERROR]:", err)
+			return
+		}
+		log.Println("[GAME] STATE LOADED FROM", path)
+	}
+}
+
+/*
+Keys:
+
+	M + ArrowUp : Selected object mass *= Mass desloc.
+	M + ArrowDown : Selected object mass /= Mass desloc.
+*/
+func SetSelectedMass(g *Game) {
+	sel := (*simul.Simulation)(g).GetSelected()
+	if sel == nil {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		sel.Mass *= g.EditOpt.MassDesloc
+		g.Universe.ResetDiagnostics()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		sel.Mass /= g.EditOpt.MassDesloc
+		g.Universe.ResetDiagnostics()
+	}
+}
+
+/*
+Keys:
+
+	N + ArrowUp : Selected object radius *= Radius desloc.
+	N + ArrowDown : Selected object radius /= Radius desloc.
+*/
+func SetSelectedRadius(g *Game) {
+	sel := (*simul.Simulation)(g).GetSelected()
+	if sel == nil {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		sel.Radius *= g.EditOpt.RadiusDesloc
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		sel.Radius /= g.EditOpt.RadiusDesloc
+	}
+}
+
+// Key: C : Switches to the next camera mode
+func SwitchCameraMode(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		g.SetCameraMode(simul.NextName(CameraModes, g.EditOpt.CameraMode))
+	}
+}
+
+// Key: F : Switches to the next trail frame
+func SwitchTrailFrame(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		g.EditOpt.TrailFrame = simul.NextName(TrailFrames, g.EditOpt.TrailFrame)
+		log.Println("[GAME] TRAIL FRAME:", g.EditOpt.TrailFrame)
+	}
+}
+
+/*
+Keys:
+
+	P + ArrowUp : Placed objects mass *= Mass desloc.
+	P + ArrowDown : Placed objects mass /= Mass desloc.
+	P + ArrowRight : Placed objects radius *= Radius desloc.
+	P + ArrowLeft : Placed objects radius /= Radius desloc.
+*/
+func SetPlaceObject(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		g.EditOpt.PlaceMass *= g.EditOpt.MassDesloc
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		g.EditOpt.PlaceMass /= g.EditOpt.MassDesloc
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		g.EditOpt.PlaceRadius *= g.EditOpt.RadiusDesloc
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		g.EditOpt.PlaceRadius /= g.EditOpt.RadiusDesloc
+	}
+}
+
+// Key: V : Switches between the projections of 3D universes
+func SwitchProjection(g *Game) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) && g.Universe.Dimensions == 3 {
+		g.EditOpt.Projection = simul.NextName(Projections, g.EditOpt.Projection)
+		log.Println("[GAME] PROJECTION:", g.EditOpt.Projection)
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"image/color"
+	"math"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+var boxColor = color.RGBA{90, 90, 90, 255}
+
+// Outline of the universe box, only drawn when the boundary isn't open
+func (g *Game) DrawBox(screen *ebiten.Image) {
+	if g.Universe.BoundaryName == "open" {
+		return
+	}
+
+	size := g.Universe.GetBoxSize()
+	corner := func(i int) simul.Coordinates3D {
+		return simul.Coordinates3D{
+			X: size.X * float64(i&1),
+			Y: size.Y * float64(i>>1&1),
+			Z: size.Z * float64(i>>2&1),
+		}
+	}
+
+	// The edges join the corners that differ on a single axis
+	corners := 4
+	if g.Universe.Dimensions == 3 {
+		corners = 8
+	}
+	for i := 0; i < corners; i++ {
+		for axis := 1; axis < corners; axis <<= 1 {
+			if i&axis != 0 {
+				continue
+			}
+			x0, y0 := g.PosToPx(corner(i))
+			x1, y1 := g.PosToPx(corner(i | axis))
+			ebitenutil.DrawLine(screen, x0, y0, x1, y1, boxColor)
+		}
+	}
+}
+
+/*
+Tells whether an object went from a to b through the border
+of a periodic universe, the line between them isn't drawn
+*/
+func (g *Game) IsWrapped(a, b simul.Coordinates3D) bool {
+	period := g.Universe.GetPeriod()
+	d := b.Sub(a)
+	return period.X > 0 && math.Abs(d.X) > period.X/2 ||
+		period.Y > 0 && math.Abs(d.Y) > period.Y/2 ||
+		period.Z > 0 && math.Abs(d.Z) > period.Z/2
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"log"
+	"math"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+	"github.com/Guilherme-De-Marchi/nbody-go/util"
+)
+
+const DEFAULT_CAMERA_MODE = "free"
+
+/*
+free : the universe as it is.
+follow : moves with the selected object.
+barycenter : moves with the center of mass of the universe.
+corotating : moves and rotates with the selected object and its companion,
+both stay on the horizontal line through their center of mass.
+*/
+var CameraModes = []string{"free", "follow", "barycenter", "corotating"}
+
+/*
+Frame of reference of the camera, objects are drawn at
+their position relative to the origin, rotated by -Angle
+*/
+type Frame struct {
+	Origin simul.Coordinates3D
+	Angle  float64
+}
+
+var (
+	frame  Frame
+	framed bool // The objects of the camera mode were found on the last update
+)
+
+func (f Frame) ToView(pos simul.Coordinates3D) simul.Coordinates3D {
+	return pos.Sub(f.Origin).Rotate(-f.Angle)
+}
+
+func (f Frame) FromView(pos simul.Coordinates3D) simul.Coordinates3D {
+	return pos.Rotate(f.Angle).Add(f.Origin)
+}
+
+// Pixels per unit of distance on each axis
+func (g *Game) GetRatio() [2]float64 {
+	return [2]float64{
+		SCREEN_WIDTH / (g.Universe.Size.X * g.EditOpt.Zoom),
+		SCREEN_HEIGHT / (g.Universe.Size.Y * g.EditOpt.Zoom),
+	}
+}
+
+func (g *Game) GetOffset() [2]float64 {
+	return [2]float64{g.EditOpt.Offset.X, g.EditOpt.Offset.Y}
+}
+
+// Screen position of a point of the universe
+func (g *Game) PosToPx(pos simul.Coordinates3D) (float64, float64) {
+	px, py, _ := g.Project(pos)
+	return px, py
+}
+
+/*
+Point of the universe under a screen position.
+On 3D universes it's the point on the plane through the
+center of rotation facing the camera.
+*/
+func (g *Game) PxToPos(px, py float64) simul.Coordinates3D {
+	x, y := util.PxToPos([2]float64{px, py}, g.GetRatio(), g.GetOffset())
+	return g.FromCamera(simul.Coordinates3D{X: x, Y: y})
+}
+
+// Moves the camera by a distance in pixels
+func (g *Game) Pan(dx, dy float64) {
+	g.EditOpt.Offset.X += dx
+	g.EditOpt.Offset.Y += dy
+}
+
+/*
+Multiplies the zoom by k keeping the point of the universe
+under the screen position (px, py) at the same place.
+k < 1 zooms in.
+*/
+func (g *Game) ZoomAt(px, py, k float64) {
+	pos := g.PxToPos(px, py)
+	g.EditOpt.Zoom *= k
+	nx, ny := g.PosToPx(pos)
+	g.Pan(nx-px, ny-py)
+}
+
+// Moves the camera so pos is on the center of the screen
+func (g *Game) CenterAt(pos simul.Coordinates3D) {
+	px, py := g.PosToPx(pos)
+	g.Pan(px-SCREEN_WIDTH/2, py-SCREEN_HEIGHT/2)
+}
+
+/*
+Returns the frame of the camera mode.
+false when the objects the mode depends on aren't available.
+*/
+func (g *Game) GetFrame() (Frame, bool) {
+	switch g.EditOpt.CameraMode {
+	case "follow":
+		if sel := (*simul.Simulation)(g).GetSelected(); sel != nil {
+			return Frame{Origin: sel.Pos}, true
+		}
+	case "barycenter":
+		if len(g.Universe.Objects) != 0 {
+			com, _ := g.Universe.GetCenterOfMass()
+			return Frame{Origin: com}, true
+		}
+	case "corotating":
+		a := (*simul.Simulation)(g).GetSelected()
+		b := (*simul.Simulation)(g).GetCompanion()
+		if a != nil && b != nil && a != b {
+			m := a.Mass + b.Mass
+			d := b.Pos.Sub(a.Pos)
+			return Frame{
+				Origin: a.Pos.Scale(a.Mass / m).Add(b.Pos.Scale(b.Mass / m)),
+				Angle:  math.Atan2(d.Y, d.X),
+			}, true
+		}
+	default:
+		return Frame{}, true
+	}
+	return Frame{}, false
+}
+
+/*
+Moves the frame of the camera, it stays still when the mode
+can't be followed. The followed point is centered on the screen
+once its objects are found.
+*/
+func (g *Game) UpdateCamera() {
+	f, ok := g.GetFrame()
+	if ok {
+		frame = f
+		if !framed && g.EditOpt.CameraMode != "free" {
+			g.CenterAt(frame.Origin)
+		}
+	}
+	framed = ok
+}
+
+/*
+Changes the camera mode. The free mode keeps the point
+on the center of the screen.
+*/
+func (g *Game) SetCameraMode(mode string) {
+	center := g.PxToPos(SCREEN_WIDTH/2, SCREEN_HEIGHT/2)
+	g.EditOpt.CameraMode = mode
+	framed = false
+	g.UpdateCamera()
+	if mode == "free" {
+		g.CenterAt(center)
+	}
+
+	if !framed {
+		log.Println("[GAME] CAMERA:", mode, "(select the objects to follow)")
+		return
+	}
+	log.Println("[GAME] CAMERA:", mode)
+}
+
+// Selects the companion of the co-rotating frame
+func (g *Game) SetCompanion(obj *simul.Object) {
+	g.Companion = obj
+	if obj != nil {
+		log.Println("[GAME] COMPANION:", obj.Name)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"image"
 	"image/color"
 	"log"
+	"math"
+	"sort"
 
 	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
 	"github.com/Guilherme-De-Marchi/nbody-go/util"
@@ -15,11 +17,27 @@ import (
 )
 
 const (
-	SCREEN_WIDTH  = 300
-	SCREEN_HEIGHT = 300
+	SCREEN_WIDTH  = 600
+	SCREEN_HEIGHT = 600
+
+	// The diagnostics take O(N^2), they are refreshed once every N ticks
+	DIAGNOSTICS_EVERY = 30
 )
 
-var circle *ebiten.Image
+var (
+	circle *ebiten.Image
+	keys   []ebiten.Key
+	ticks  int
+
+	diagnostics simul.Diagnostics
+	drift       simul.Drift
+
+	energyPlot   = &Series{Name: "Energy drift"}
+	objectsPlot  = &Series{Name: "Objects"}
+	tpsPlot      = &Series{Name: "TPS"}
+	distancePlot = &Series{Name: "Selected to center of mass"}
+	plots        = []*Series{energyPlot, objectsPlot, tpsPlot, distancePlot}
+)
 
 type Game simul.Simulation
 
@@ -27,15 +45,24 @@ func (g *Game) Init() error {
 	g.WinGradientImage = image.NewRGBA(image.Rect(0, 0, SCREEN_WIDTH, SCREEN_HEIGHT))
 	g.TotalGradientImage = image.NewRGBA(image.Rect(0, 0, int(g.Universe.Size.X), int(g.Universe.Size.Y)))
 
+	if g.EditOpt.CameraMode == "" {
+		g.EditOpt.CameraMode = DEFAULT_CAMERA_MODE
+	}
+	if g.EditOpt.TrailFrame == "" {
+		g.EditOpt.TrailFrame = DEFAULT_TRAIL_FRAME
+	}
+	if g.EditOpt.Projection == "" {
+		g.EditOpt.Projection = DEFAULT_PROJECTION
+	}
+	g.InitPlaceOpt()
+
 	ebiten.SetWindowSize(SCREEN_WIDTH, SCREEN_HEIGHT)
 	ebiten.SetWindowTitle("Gravity Simulator")
 
 	log.Println("[GAME] PRESS 'ESCAPE' TO SEE THE CONTROLS")
-	if err := ebiten.RunGame(g); err != nil {
-		return err
-	}
-
-	return nil
+	err := ebiten.RunGame(g)
+	g.StopRecording()
+	return err
 }
 
 func (g *Game) Update() error {
@@ -47,28 +74,109 @@ func (g *Game) Update() error {
 		g.UpdateTotalGravityGrad()
 	}
 
-	g.Keys = inpututil.AppendPressedKeys(g.Keys[:0])
+	keys = inpututil.AppendPressedKeys(keys[:0])
 
-	for _, k := range g.Keys {
+	for _, k := range keys {
 		if f, ok := KeyMap[k]; ok {
 			f(g)
 		}
 	}
-
 	if !g.EditOpt.ShowPauseScreen {
-		g.Universe.ApplyGravity()
+		g.UpdateMouse()
+		g.UpdatePlacing()
+	}
+
+	stepped := !g.EditOpt.ShowPauseScreen
+	if stepped {
+		g.Universe.Step()
+		g.Record()
+		g.UpdatePlots()
+		g.UpdateTrails()
 	}
+	g.UpdateCamera()
+	g.UpdateDiagnostics(stepped)
 	return nil
 }
 
+/*
+Refreshes the diagnostics shown on the debug screen.
+The reference of the drift is always taken right away,
+even with the debug screen hidden.
+The energy drift is only plotted when the universe stepped.
+*/
+func (g *Game) UpdateDiagnostics(stepped bool) {
+	show := g.EditOpt.ShowDebug || g.EditOpt.ShowPlots
+	if show && ticks%DIAGNOSTICS_EVERY == 0 || g.Universe.Reference == nil {
+		diagnostics, drift = g.Universe.GetDrift()
+		if stepped {
+			energyPlot.Add(diagnostics.Time, drift.Energy, g.GetPlotWindow())
+		}
+	}
+	ticks++
+}
+
+// Samples the plotted values, the energy drift is sampled with the diagnostics
+func (g *Game) UpdatePlots() {
+	t := g.Universe.Time
+	window := g.GetPlotWindow()
+	objectsPlot.Add(t, float64(len(g.Universe.Objects)), window)
+	tpsPlot.Add(t, ebiten.CurrentTPS(), window)
+
+	if sel := (*simul.Simulation)(g).GetSelected(); sel != nil {
+		com, _ := g.Universe.GetCenterOfMass()
+		distancePlot.Add(t, sel.Pos.Sub(com).Len(), window)
+	}
+}
+
+// Simulated time shown on the plots
+func (g *Game) GetPlotWindow() float64 {
+	if g.EditOpt.PlotWindow > 0 {
+		return g.EditOpt.PlotWindow
+	}
+	return DEFAULT_PLOT_WINDOW_STEPS * g.Universe.Dt
+}
+
+func (g *Game) StartRecording() {
+	if g.OutputOpt.Path == "" {
+		log.Println("[GAME] NO OUTPUT PATH ON THE CONFIGURATION")
+		return
+	}
+	rec, err := simul.NewRecorder(g.OutputOpt)
+	if err != nil {
+		log.Println("[GAME ERROR]:", err)
+		return
+	}
+	g.Recorder = rec
+	log.Println("[GAME] RECORDING TO", rec.Path)
+	g.Record()
+}
+
+func (g *Game) StopRecording() {
+	if g.Recorder == nil {
+		return
+	}
+	if err := g.Recorder.Close(); err != nil {
+		log.Println("[GAME ERROR]:", err)
+	}
+	log.Println("[GAME] RECORDING SAVED TO", g.Recorder.Path)
+	g.Recorder = nil
+}
+
+func (g *Game) Record() {
+	if g.Recorder == nil {
+		return
+	}
+	if err := g.Recorder.Record(g.Universe); err != nil {
+		log.Println("[GAME ERROR]:", err)
+		g.StopRecording()
+	}
+}
+
 func (g *Game) UpdateWinGravityGrad() {
-	rx := SCREEN_WIDTH / (g.Universe.Size.X * g.EditOpt.Zoom)
-	ry := SCREEN_HEIGHT / (g.Universe.Size.Y * g.EditOpt.Zoom)
 	gradient, high := g.Universe.GetViewGravityGradient(
 		g.EditOpt.GradExp,
 		[2]float64{SCREEN_WIDTH, SCREEN_HEIGHT},
-		[2]float64{rx, ry},
-		[2]float64{g.EditOpt.Offset.X, g.EditOpt.Offset.Y},
+		g.PxToPos,
 	)
 	// log.Println(high)
 
@@ -118,68 +226,158 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		g.DrawTotalGravGrad(screen)
 	}
 
+	g.DrawBox(screen)
+
+	if g.EditOpt.ShowTrails {
+		g.DrawTrails(screen)
+	}
+
 	if g.EditOpt.ShowObject {
 		g.DrawObject(screen)
+		g.DrawSelection(screen)
 	}
 
 	if g.EditOpt.ShowDebug {
 		g.DrawDebug(screen)
 	}
+
+	if g.EditOpt.ShowPlots {
+		g.DrawPlots(screen)
+	}
+
+	g.DrawPlacing(screen)
+	g.DrawInspector(screen)
 }
 
+// Commands split in two columns, the view ones and the simulation ones
 func (g *Game) DrawPauseScreen(screen *ebiten.Image) {
-	ebitenutil.DebugPrintAt(screen, "W : Move Up", 0, 0)
-	ebitenutil.DebugPrintAt(screen, "A : Move Left", 0, 15)
-	ebitenutil.DebugPrintAt(screen, "S : Move Down", 0, 30)
-	ebitenutil.DebugPrintAt(screen, "D : Move Right", 0, 45)
-
-	ebitenutil.DebugPrintAt(screen, "1 : Show Debug informations", 0, 75)
-	ebitenutil.DebugPrintAt(screen, "2 : Show Objects", 0, 90)
-	ebitenutil.DebugPrintAt(screen, "3 : Show Objects name", 0, 105)
-	ebitenutil.DebugPrintAt(screen, "4 : Show Gravity Gradient (objects on windows) [DROPS TPS]", 0, 120)
-	ebitenutil.DebugPrintAt(screen, "Escape : Show Pause Screen", 0, 135)
-
-	ebitenutil.DebugPrintAt(screen, "R : Generate a New Random Universe", 0, 165)
-	ebitenutil.DebugPrintAt(screen, "Z + ArrowUp : Increases Zoom", 0, 180)
-	ebitenutil.DebugPrintAt(screen, "Z + ArrowDown : Decreases Zoom", 0, 195)
-	ebitenutil.DebugPrintAt(screen, "G + ArrowUp : Increases Gravitational Constant", 0, 210)
-	ebitenutil.DebugPrintAt(screen, "G + ArrowDown : Decreases Gravitational Constant", 0, 225)
-	ebitenutil.DebugPrintAt(screen, "O + ArrowUp : Add N Objects", 0, 240)
-	ebitenutil.DebugPrintAt(screen, "O + ArrowDown : Remove N Objects", 0, 255)
-	ebitenutil.DebugPrintAt(screen, "E + ArrowUp : Increases Gradient Exp", 0, 270)
-	ebitenutil.DebugPrintAt(screen, "E + ArrowDown : Decreases Gradient Exp", 0, 285)
+	columns := [][]string{{
+		"Mouse Drag : Move the Camera",
+		"Mouse Wheel : Zoom Around the Cursor",
+		"Mouse Click : Select an Object",
+		"W : Move Up",
+		"A : Move Left",
+		"S : Move Down",
+		"D : Move Right",
+		"",
+		"1 : Show Debug informations",
+		"2 : Show Objects",
+		"3 : Show Objects name",
+		"4 : Show Gravity Gradient [DROPS TPS]",
+		"6 : Show Plots",
+		"7 : Show Trails",
+		"F : Switch Trail Frame",
+		"Tab : Select the Next Object",
+		"Shift + Click : Select the Companion",
+		"Right Drag : Place an Object (drag = velocity)",
+		"P + Up/Down : Mass of the Placed Objects",
+		"P + Right/Left : Radius of the Placed Objects",
+		"C : Switch Camera Mode",
+		"Ctrl + Mouse Drag : Rotate the View (3D)",
+		"V : Switch Projection (3D)",
+		"Escape : Show Pause Screen",
+	}, {
+		"R : Generate a New Random Universe",
+		"Z + ArrowUp : Increases Zoom",
+		"Z + ArrowDown : Decreases Zoom",
+		"G + ArrowUp : Increases Gravitational Constant",
+		"G + ArrowDown : Decreases Gravitational Constant",
+		"T + ArrowUp : Increases Time Step",
+		"T + ArrowDown : Decreases Time Step",
+		"O + ArrowUp : Add N Objects",
+		"O + ArrowDown : Remove N Objects",
+		"E + ArrowUp : Increases Gradient Exp",
+		"E + ArrowDown : Decreases Gradient Exp",
+		"M + Up/Down : Mass of the Selected Object",
+		"N + Up/Down : Radius of the Selected Object",
+		"I : Switch Integrator",
+		"B : Switch Force Solver",
+		"X : Switch Collision Mode",
+		"K : Switch Boundary Condition",
+		"F5 : Quick Save",
+		"F9 : Quick Load",
+		"F8 : Start/Stop Recording the Trajectory",
+	}}
+	for c, lines := range columns {
+		for i, l := range lines {
+			ebitenutil.DebugPrintAt(screen, l, c*SCREEN_WIDTH/len(columns), i*15)
+		}
+	}
 }
 
 func (g *Game) DrawDebug(screen *ebiten.Image) {
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %0.2f", ebiten.CurrentTPS()), 0, 0)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Zoom: %v", 1/g.EditOpt.Zoom), 0, 15)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Offset: %vx  %vy", g.EditOpt.Offset.X, g.EditOpt.Offset.Y), 0, 30)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Universe size: %vx  %vy", g.Universe.Size.X, g.Universe.Size.Y), 0, 45)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Amount of objects: %v", len(g.Universe.Objects)), 0, 60)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Gravitational constant: %v", g.Universe.Gconst), 0, 75)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Gradient exp: %v", g.EditOpt.GradExp), 0, 90)
+	lines := []string{
+		fmt.Sprintf("TPS: %0.2f", ebiten.CurrentTPS()),
+		fmt.Sprintf("Zoom: %v", 1/g.EditOpt.Zoom),
+		fmt.Sprintf("Offset: %vx  %vy", g.EditOpt.Offset.X, g.EditOpt.Offset.Y),
+		fmt.Sprintf("Universe size: %vx  %vy  %vz", g.Universe.Size.X, g.Universe.Size.Y, g.Universe.Size.Z),
+		fmt.Sprintf("Dimensions: %v  projection: %v", g.Universe.Dimensions, g.GetProjectionName()),
+		fmt.Sprintf("Amount of objects: %v  seed: %v", len(g.Universe.Objects), g.Universe.Seed),
+		fmt.Sprintf("Gravitational constant: %v", g.Universe.Gconst),
+		fmt.Sprintf("Integrator: %v", g.Universe.IntegratorName),
+		fmt.Sprintf("Force solver: %v  theta: %v", g.Universe.SolverName, g.Universe.Theta),
+		fmt.Sprintf("Parallel: %v  workers: %v", g.Universe.Parallel, g.Universe.Workers),
+		fmt.Sprintf("Softening: %v  kernel: %v", g.Universe.Softening.Length, g.Universe.Softening.Kernel),
+		fmt.Sprintf("Collision: %v  restitution: %v  count: %v", g.Universe.CollisionName, g.Universe.Restitution, g.Universe.Collisions),
+		fmt.Sprintf("Boundary: %v  absorbed: %v", g.Universe.BoundaryName, g.Universe.Absorbed),
+		fmt.Sprintf("Recording: %v", g.Recorder != nil),
+		fmt.Sprintf("Selected: %v", g.GetSelectedName()),
+		fmt.Sprintf("Camera: %v", g.EditOpt.CameraMode),
+		fmt.Sprintf("Trails: %v  frame: %v", g.EditOpt.ShowTrails, g.EditOpt.TrailFrame),
+		fmt.Sprintf("Place mass: %v  radius: %v", g.EditOpt.PlaceMass, g.EditOpt.PlaceRadius),
+		fmt.Sprintf("Time step: %v", g.Universe.Dt),
+		fmt.Sprintf("Adaptive time step: %v  block: %v", g.Universe.TimeStep.Adaptive, g.Universe.TimeStep.BlockSteps),
+		fmt.Sprintf("Last step: %v", g.Universe.StepDt),
+		fmt.Sprintf("Simulated time: %0.2f", g.Universe.Time),
+		fmt.Sprintf("Energy: %.6e  K: %.3e  U: %.3e  drift: %.2e", diagnostics.Energy, diagnostics.Kinetic, diagnostics.Potential, drift.Energy),
+		fmt.Sprintf("Momentum: %.3ex  %.3ey  %.3ez  drift: %.2e", diagnostics.Momentum.X, diagnostics.Momentum.Y, diagnostics.Momentum.Z, drift.Momentum),
+		fmt.Sprintf("Angular momentum: %.3ex  %.3ey  %.3ez  drift: %.2e", diagnostics.AngularMomentum.X, diagnostics.AngularMomentum.Y, diagnostics.AngularMomentum.Z, drift.AngularMomentum),
+		fmt.Sprintf("Center of mass: %.2fx  %.2fy  %.2fz  drift: %.2e", diagnostics.CenterOfMass.X, diagnostics.CenterOfMass.Y, diagnostics.CenterOfMass.Z, drift.CenterOfMass),
+		fmt.Sprintf("Gradient exp: %v", g.EditOpt.GradExp),
+
+		fmt.Sprintf("Show objects: %v", g.EditOpt.ShowObject),
+		fmt.Sprintf("Show objects name: %v", g.EditOpt.ShowObjectName),
+		fmt.Sprintf("Show gravitational gradient: %v", g.EditOpt.ShowWinGravityGrad),
+	}
+	for i, l := range lines {
+		ebitenutil.DebugPrintAt(screen, l, 0, i*15)
+	}
+}
+
+// Plots stacked on the right side of the screen
+func (g *Game) DrawPlots(screen *ebiten.Image) {
+	window := g.GetPlotWindow()
+	x := float64(SCREEN_WIDTH - PLOT_WIDTH - PLOT_MARGIN)
+	for i, p := range plots {
+		p.Draw(screen, x, float64(PLOT_MARGIN+i*(PLOT_HEIGHT+PLOT_MARGIN)), window)
+	}
+}
 
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Show objects: %v", g.EditOpt.ShowObject), 0, 105)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Show objects name: %v", g.EditOpt.ShowObjectName), 0, 120)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Show gravitational gradient: %v", g.EditOpt.ShowWinGravityGrad), 0, 135)
+func (g *Game) GetSelectedName() string {
+	if sel := (*simul.Simulation)(g).GetSelected(); sel != nil {
+		return sel.Name
+	}
+	return "none"
 }
 
 func (g *Game) DrawObject(screen *ebiten.Image) {
-	rx := SCREEN_WIDTH / (g.Universe.Size.X * g.EditOpt.Zoom)
-	ry := SCREEN_HEIGHT / (g.Universe.Size.Y * g.EditOpt.Zoom)
+	r := g.GetRatio()
 
 	var ctx *gg.Context
-	for _, obj := range g.Universe.Objects {
-		// Fix this
-		px, py := util.PosToPx(
-			[2]float64{obj.Pos.X - obj.Radius, obj.Pos.Y - obj.Radius},
-			[2]float64{rx, ry},
-			[2]float64{g.EditOpt.Offset.X, g.EditOpt.Offset.Y},
-		)
-
-		lx := int(obj.Radius * 2 * rx)
-		ly := int(obj.Radius * 2 * ry)
-		if lx <= 0 || ly <= 0 {
+	for _, obj := range g.GetDrawOrder() {
+		px, py, k := g.Project(obj.Pos)
+		if k <= 0 {
+			continue
+		}
+
+		// Objects smaller than a pixel are still drawn
+		lx := int(math.Max(obj.Radius*2*r[0]*k, 2))
+		ly := int(math.Max(obj.Radius*2*r[1]*k, 2))
+
+		// Top left corner, the camera frame may be rotated
+		px -= float64(lx / 2)
+		py -= float64(ly / 2)
+		if px+float64(lx) < 0 || py+float64(ly) < 0 || px > SCREEN_WIDTH || py > SCREEN_HEIGHT {
 			continue
 		}
 
@@ -199,12 +397,30 @@ func (g *Game) DrawObject(screen *ebiten.Image) {
 	}
 }
 
+/*
+Objects of 3D universes sorted from the farthest to the
+closest to the camera, so the closest are drawn on top
+*/
+func (g *Game) GetDrawOrder() []*simul.Object {
+	if g.Universe.Dimensions != 3 {
+		return g.Universe.Objects
+	}
+
+	objs := make([]*simul.Object, len(g.Universe.Objects))
+	depth := make(map[*simul.Object]float64, len(objs))
+	for i, obj := range g.Universe.Objects {
+		objs[i] = obj
+		depth[obj] = g.ToCamera(obj.Pos).Z
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return depth[objs[i]] < depth[objs[j]]
+	})
+	return objs
+}
+
+// The other properties are shown by the inspector of the selected object
 func (g *Game) DrawObjectName(screen *ebiten.Image, obj *simul.Object, px, py float64) {
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Name: %v", obj.Name), int(px), int(py-15))
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Mass: %v", obj.Mass), int(px), int(py-30))
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Radius: %v", obj.Radius), int(px), int(py-45))
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Velocity: %v", obj.Vel), int(px), int(py-60))
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Acceleration: %v", obj.Accel), int(px), int(py-75))
+	ebitenutil.DebugPrintAt(screen, obj.Name, int(px), int(py-15))
 }
 
 func (g *Game) DrawWinGravGrad(screen *ebiten.Image) {
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+const (
+	// Objects smaller than this, in pixels, can still be clicked
+	CLICK_RADIUS = 6
+
+	INSPECTOR_WIDTH  = 260
+	INSPECTOR_MARGIN = 10
+)
+
+var (
+	selectionColor = color.RGBA{255, 220, 0, 255}
+)
+
+// Selects obj, nil clears the selection
+func (g *Game) Select(obj *simul.Object) {
+	if obj != g.Selected {
+		distancePlot.Clear()
+	}
+	g.Selected = obj
+	if obj != nil {
+		log.Println("[GAME] SELECTED:", obj.Name)
+	}
+}
+
+/*
+Returns the object drawn under the screen position (px, py),
+the closest to the camera when they overlap
+*/
+func (g *Game) GetObjectAt(px, py float64) *simul.Object {
+	r := g.GetRatio()
+	var found *simul.Object
+	objs := g.GetDrawOrder()
+	best := math.Inf(1)
+	for i := len(objs) - 1; i >= 0; i-- {
+		obj := objs[i]
+		x, y, k := g.Project(obj.Pos)
+		if k <= 0 {
+			continue
+		}
+		d := math.Hypot(x-px, y-py)
+		if d <= math.Max(obj.Radius*math.Max(r[0], r[1])*k, CLICK_RADIUS) && d < best {
+			found, best = obj, d
+			if g.Universe.Dimensions == 3 {
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Draws a square around the selected object
+func (g *Game) DrawSelection(screen *ebiten.Image) {
+	sel := (*simul.Simulation)(g).GetSelected()
+	if sel == nil {
+		return
+	}
+
+	r := g.GetRatio()
+	px, py, k := g.Project(sel.Pos)
+	if k <= 0 {
+		return
+	}
+	hx := math.Max(sel.Radius*r[0]*k, 2) + 3
+	hy := math.Max(sel.Radius*r[1]*k, 2) + 3
+	ebitenutil.DrawLine(screen, px-hx, py-hy, px+hx, py-hy, selectionColor)
+	ebitenutil.DrawLine(screen, px-hx, py+hy, px+hx, py+hy, selectionColor)
+	ebitenutil.DrawLine(screen, px-hx, py-hy, px-hx, py+hy, selectionColor)
+	ebitenutil.DrawLine(screen, px+hx, py-hy, px+hx, py+hy, selectionColor)
+}
+
+// Panel with the properties of the selected object
+func (g *Game) DrawInspector(screen *ebiten.Image) {
+	sel := (*simul.Simulation)(g).GetSelected()
+	if sel == nil {
+		return
+	}
+
+	nearest := "none"
+	if obj, d := g.Universe.GetNearest(sel); obj != nil {
+		nearest = fmt.Sprintf("%v (%.4g)", obj.Name, d)
+	}
+	lines := []string{
+		fmt.Sprintf("Name: %v", sel.Name),
+		fmt.Sprintf("Mass: %.6g", sel.Mass),
+		fmt.Sprintf("Radius: %.6g", sel.Radius),
+		fmt.Sprintf("Position: %.4gx  %.4gy  %.4gz", sel.Pos.X, sel.Pos.Y, sel.Pos.Z),
+		fmt.Sprintf("Speed: %.4g", sel.Vel.Len()),
+		fmt.Sprintf("Acceleration: %.4g", sel.Accel.Len()),
+		fmt.Sprintf("Kinetic energy: %.4g", 0.5*sel.Mass*sel.Vel.Dot(sel.Vel)),
+		fmt.Sprintf("Nearest: %v", nearest),
+		"M/N + Arrows : Edit Mass/Radius",
+	}
+
+	h := float64(len(lines)*15 + 6)
+	x := float64(INSPECTOR_MARGIN)
+	y := SCREEN_HEIGHT - INSPECTOR_MARGIN - h
+	ebitenutil.DrawRect(screen, x, y, INSPECTOR_WIDTH, h, plotBackground)
+	ebitenutil.DrawLine(screen, x, y, x+INSPECTOR_WIDTH, y, selectionColor)
+	for i, l := range lines {
+		ebitenutil.DebugPrintAt(screen, l, int(x)+4, int(y)+3+i*15)
+	}
+}
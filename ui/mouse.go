@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// Distance in pixels the cursor must move before a press becomes a drag
+	DRAG_THRESHOLD = 4
+	// Zoom multiplier of each step of the mouse wheel
+	WHEEL_ZOOM_FACTOR = 1.1
+)
+
+var (
+	dragging   bool
+	dragMoved  bool
+	dragButton ebiten.MouseButton
+	dragStart  [2]int
+	lastCursor [2]int
+)
+
+func isPanButtonPressed() bool {
+	return ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) ||
+		ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle)
+}
+
+/*
+Mouse:
+
+	Left click : Selects the object under the cursor.
+	Shift + Left click : Selects the companion of the co-rotating camera.
+	Left or middle drag : Moves the camera.
+	Ctrl + Left drag : Rotates the view of 3D universes.
+	Wheel : Zooms around the cursor.
+*/
+func (g *Game) UpdateMouse() {
+	x, y := ebiten.CursorPosition()
+
+	for _, b := range [...]ebiten.MouseButton{ebiten.MouseButtonLeft, ebiten.MouseButtonMiddle} {
+		if inpututil.IsMouseButtonJustPressed(b) {
+			dragging, dragMoved, dragButton = true, false, b
+			dragStart = [2]int{x, y}
+			lastCursor = dragStart
+		}
+	}
+
+	if dragging {
+		if !isPanButtonPressed() {
+			dragging = false
+			// A press that didn't move is a click
+			if !dragMoved && dragButton == ebiten.MouseButtonLeft {
+				if ebiten.IsKeyPressed(ebiten.KeyShift) {
+					g.SetCompanion(g.GetObjectAt(float64(x), float64(y)))
+				} else {
+					g.Select(g.GetObjectAt(float64(x), float64(y)))
+				}
+			}
+		} else {
+			if !dragMoved && math.Hypot(float64(x-dragStart[0]), float64(y-dragStart[1])) > DRAG_THRESHOLD {
+				dragMoved = true
+			}
+			if dragMoved {
+				dx, dy := float64(lastCursor[0]-x), float64(lastCursor[1]-y)
+				if dragButton == ebiten.MouseButtonLeft && ebiten.IsKeyPressed(ebiten.KeyControl) {
+					g.RotateView(-dx, -dy)
+				} else {
+					g.Pan(dx, dy)
+				}
+			}
+			lastCursor = [2]int{x, y}
+		}
+	}
+
+	if _, wy := ebiten.Wheel(); wy != 0 {
+		g.ZoomAt(float64(x), float64(y), math.Pow(WHEEL_ZOOM_FACTOR, -wy))
+	}
+}
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+	"github.com/Guilherme-De-Marchi/nbody-go/util"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// Steps to travel the dragged distance when the velocity scale isn't set
+	PLACE_DRAG_STEPS = 100
+
+	DEFAULT_PREDICT_STEPS = 300
+	// Ticks between two predictions while the cursor doesn't move
+	PREDICT_EVERY = 10
+)
+
+var (
+	placing    bool
+	placePos   simul.Coordinates3D
+	placeDrag  [2]int
+	prediction []simul.Coordinates3D
+	predictAge int
+
+	placeColor      = color.RGBA{255, 255, 255, 255}
+	predictionColor = color.RGBA{120, 160, 255, 200}
+)
+
+// Fills the unset placing options using the random options
+func (g *Game) InitPlaceOpt() {
+	if g.EditOpt.PlaceMass <= 0 {
+		g.EditOpt.PlaceMass = math.Sqrt(math.Max(g.RandOpt.MassR[0], 1) * math.Max(g.RandOpt.MassR[1], 1))
+	}
+	if g.EditOpt.PlaceRadius <= 0 {
+		g.EditOpt.PlaceRadius = math.Max((g.RandOpt.RadR[0]+g.RandOpt.RadR[1])/2, 1)
+	}
+	if g.EditOpt.PredictSteps <= 0 {
+		g.EditOpt.PredictSteps = DEFAULT_PREDICT_STEPS
+	}
+}
+
+// Velocity given to the placed object by each unit of dragged distance
+func (g *Game) GetPlaceVelocityScale() float64 {
+	if g.EditOpt.PlaceVelScale > 0 {
+		return g.EditOpt.PlaceVelScale
+	}
+	return 1 / (PLACE_DRAG_STEPS * g.Universe.Dt)
+}
+
+// Velocity of the object placed with the cursor at (x, y)
+func (g *Game) GetPlaceVelocity(x, y int) simul.Coordinates3D {
+	return g.PxToPos(float64(x), float64(y)).Sub(placePos).Scale(g.GetPlaceVelocityScale())
+}
+
+/*
+Object that would be placed with the cursor at (x, y).
+The name is only given on release, so the previews don't
+take values from the random source of the universe.
+*/
+func (g *Game) GetPlaceObject(name string, x, y int) *simul.Object {
+	obj := simul.NewObject(name, placeColor, placePos, g.EditOpt.PlaceMass, g.EditOpt.PlaceRadius)
+	obj.Vel = g.GetPlaceVelocity(x, y)
+	return obj
+}
+
+/*
+Mouse:
+
+	Right press : Sets the position of the new object.
+	Right drag : Sets its velocity, shown by the arrow.
+	Right release : Adds the object to the universe.
+*/
+func (g *Game) UpdatePlacing() {
+	x, y := ebiten.CursorPosition()
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		placing = true
+		placePos = g.PxToPos(float64(x), float64(y))
+		prediction = nil
+	}
+	if !placing {
+		return
+	}
+
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) {
+		placing = false
+		prediction = nil
+		obj := g.GetPlaceObject(util.RandString(g.Universe.GetRand(), 8), x, y)
+		g.Universe.AddObjects(obj)
+		g.Universe.ApplyGravityTo([]*simul.Object{obj})
+		log.Printf("[GAME] PLACED %s: mass %v radius %v velocity %v\n", obj.Name, obj.Mass, obj.Radius, obj.Vel)
+		return
+	}
+
+	// The universe keeps moving, the prediction is refreshed from time to time
+	if prediction == nil || placeDrag != [2]int{x, y} || predictAge >= PREDICT_EVERY {
+		prediction = g.Universe.PredictTrajectory(g.GetPlaceObject("", x, y), g.EditOpt.PredictSteps)
+		placeDrag = [2]int{x, y}
+		predictAge = 0
+	}
+	predictAge++
+}
+
+// Draws the object being placed, its velocity arrow and predicted trajectory
+func (g *Game) DrawPlacing(screen *ebiten.Image) {
+	if !placing {
+		return
+	}
+
+	for i := 1; i < len(prediction); i++ {
+		if g.IsWrapped(prediction[i-1], prediction[i]) {
+			continue
+		}
+		x0, y0 := g.PosToPx(prediction[i-1])
+		x1, y1 := g.PosToPx(prediction[i])
+		ebitenutil.DrawLine(screen, x0, y0, x1, y1, predictionColor)
+	}
+
+	r := g.GetRatio()
+	px, py := g.PosToPx(placePos)
+	hx := math.Max(g.EditOpt.PlaceRadius*r[0], 2)
+	hy := math.Max(g.EditOpt.PlaceRadius*r[1], 2)
+	ebitenutil.DrawRect(screen, px-hx, py-hy, 2*hx, 2*hy, placeColor)
+
+	// Arrow from the object to the cursor
+	x, y := ebiten.CursorPosition()
+	cx, cy := float64(x), float64(y)
+	ebitenutil.DrawLine(screen, px, py, cx, cy, placeColor)
+	if l := math.Hypot(cx-px, cy-py); l > 0 {
+		ux, uy := (cx-px)/l, (cy-py)/l
+		for _, s := range [...]float64{1, -1} {
+			ebitenutil.DrawLine(screen, cx, cy, cx-8*ux+s*4*uy, cy-8*uy-s*4*ux, placeColor)
+		}
+	}
+
+	v := g.GetPlaceVelocity(x, y)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("v: %.4g", v.Len()), x+8, y+8)
+}
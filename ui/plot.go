@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+const (
+	PLOT_WIDTH       = 200
+	PLOT_HEIGHT      = 50
+	PLOT_MARGIN      = 10
+	PLOT_MAX_SAMPLES = 2000
+
+	// Window of simulated time shown, in steps, when the plot window isn't set
+	DEFAULT_PLOT_WINDOW_STEPS = 500
+)
+
+var (
+	plotBackground = color.RGBA{0, 0, 0, 180}
+	plotBorder     = color.RGBA{90, 90, 90, 255}
+	plotLine       = color.RGBA{80, 200, 120, 255}
+)
+
+// Samples of a value over the simulated time
+type Series struct {
+	Name string
+	T    []float64
+	V    []float64
+}
+
+/*
+Appends a sample and drops the ones older than the window.
+Going back in time (reset or loaded state) clears the series.
+*/
+func (s *Series) Add(t, v, window float64) {
+	if n := len(s.T); n > 0 && t < s.T[n-1] {
+		s.Clear()
+	}
+	s.T = append(s.T, t)
+	s.V = append(s.V, v)
+
+	i := 0
+	for i < len(s.T)-1 && (s.T[i] < t-window || len(s.T)-i > PLOT_MAX_SAMPLES) {
+		i++
+	}
+	if i > 0 {
+		s.T = append(s.T[:0], s.T[i:]...)
+		s.V = append(s.V[:0], s.V[i:]...)
+	}
+}
+
+func (s *Series) Clear() {
+	s.T = s.T[:0]
+	s.V = s.V[:0]
+}
+
+// Draws the series inside the box of top left corner (x, y)
+func (s *Series) Draw(screen *ebiten.Image, x, y, window float64) {
+	ebitenutil.DrawRect(screen, x, y, PLOT_WIDTH, PLOT_HEIGHT, plotBackground)
+	ebitenutil.DrawLine(screen, x, y, x+PLOT_WIDTH, y, plotBorder)
+	ebitenutil.DrawLine(screen, x, y+PLOT_HEIGHT, x+PLOT_WIDTH, y+PLOT_HEIGHT, plotBorder)
+	ebitenutil.DrawLine(screen, x, y, x, y+PLOT_HEIGHT, plotBorder)
+	ebitenutil.DrawLine(screen, x+PLOT_WIDTH, y, x+PLOT_WIDTH, y+PLOT_HEIGHT, plotBorder)
+
+	if len(s.V) == 0 {
+		ebitenutil.DebugPrintAt(screen, s.Name, int(x)+2, int(y)+2)
+		return
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range s.V {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	vr := high - low
+	if vr == 0 {
+		vr = 1
+	}
+
+	// The newest sample is always on the right border
+	end := s.T[len(s.T)-1]
+	px := func(i int) (float64, float64) {
+		return x + PLOT_WIDTH - (end-s.T[i])/window*PLOT_WIDTH,
+			y + PLOT_HEIGHT - 1 - (s.V[i]-low)/vr*(PLOT_HEIGHT-2)
+	}
+	x0, y0 := px(0)
+	for i := 1; i < len(s.V); i++ {
+		x1, y1 := px(i)
+		ebitenutil.DrawLine(screen, x0, y0, x1, y1, plotLine)
+		x0, y0 = x1, y1
+	}
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%s: %.3g", s.Name, s.V[len(s.V)-1]), int(x)+2, int(y)+2)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%.3g - %.3g", low, high), int(x)+2, int(y)+PLOT_HEIGHT-16)
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"math"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+	"github.com/Guilherme-De-Marchi/nbody-go/util"
+)
+
+const (
+	DEFAULT_PROJECTION = "orthographic"
+
+	// Distance between the perspective camera and the center of rotation, in universe sizes
+	PERSPECTIVE_DISTANCE = 2
+	// Radians rotated by each dragged pixel
+	ROTATE_SPEED = 0.01
+)
+
+// Projections of 3D universes, 2D ones are always orthographic
+var Projections = []string{"orthographic", "perspective"}
+
+/*
+Center of the rotation of the view, on the view coordinates.
+The center of the universe on the free camera mode and the
+followed point on the others.
+*/
+func (g *Game) GetPivot() simul.Coordinates3D {
+	if g.EditOpt.CameraMode == "free" {
+		return g.Universe.Size.Scale(0.5)
+	}
+	return simul.Coordinates3D{}
+}
+
+/*
+Position relative to the camera: x and y follow the screen
+and z is the depth from the center of rotation, growing to
+the viewer. Same as the frame view on 2D universes.
+*/
+func (g *Game) ToCamera(pos simul.Coordinates3D) simul.Coordinates3D {
+	v := frame.ToView(pos)
+	if g.Universe.Dimensions != 3 {
+		return v
+	}
+	p := g.GetPivot()
+	v = v.Sub(p).RotateY(g.EditOpt.ViewYaw).RotateX(g.EditOpt.ViewPitch)
+	v.X += p.X
+	v.Y += p.Y
+	return v
+}
+
+// Inverse of ToCamera
+func (g *Game) FromCamera(v simul.Coordinates3D) simul.Coordinates3D {
+	if g.Universe.Dimensions == 3 {
+		p := g.GetPivot()
+		v.X -= p.X
+		v.Y -= p.Y
+		v = v.RotateX(-g.EditOpt.ViewPitch).RotateY(-g.EditOpt.ViewYaw).Add(p)
+	}
+	return frame.FromView(v)
+}
+
+/*
+Scale of the objects at the depth z, 1 on the orthographic
+projection and 0 behind the perspective camera
+*/
+func (g *Game) GetPerspective(z float64) float64 {
+	if g.Universe.Dimensions != 3 || g.EditOpt.Projection != "perspective" {
+		return 1
+	}
+	s := g.Universe.Size
+	d := PERSPECTIVE_DISTANCE * math.Max(s.X, math.Max(s.Y, s.Z))
+	if z >= d {
+		return 0
+	}
+	return d / (d - z)
+}
+
+/*
+Returns the screen position of a point of the universe and the
+scale of the perspective on it. The perspective converges
+to the center of the screen.
+*/
+func (g *Game) Project(pos simul.Coordinates3D) (float64, float64, float64) {
+	v := g.ToCamera(pos)
+	r, off := g.GetRatio(), g.GetOffset()
+	k := g.GetPerspective(v.Z)
+	if k != 1 {
+		cx, cy := util.PxToPos([2]float64{SCREEN_WIDTH / 2, SCREEN_HEIGHT / 2}, r, off)
+		v.X = cx + (v.X-cx)*k
+		v.Y = cy + (v.Y-cy)*k
+	}
+	px, py := util.PosToPx([2]float64{v.X, v.Y}, r, off)
+	return px, py, k
+}
+
+func (g *Game) GetProjectionName() string {
+	if g.Universe.Dimensions != 3 {
+		return "orthographic"
+	}
+	return g.EditOpt.Projection
+}
+
+// Rotates the view of 3D universes by the dragged distance in pixels
+func (g *Game) RotateView(dx, dy float64) {
+	if g.Universe.Dimensions != 3 {
+		return
+	}
+	g.EditOpt.ViewYaw += dx * ROTATE_SPEED
+	g.EditOpt.ViewPitch += dy * ROTATE_SPEED
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"image/color"
+
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+const DEFAULT_TRAIL_FRAME = "universe"
+
+/*
+universe : positions as they were.
+barycenter : positions relative to the center of mass.
+selected : positions relative to the selected object.
+The relative trails are drawn around the current position of the reference.
+*/
+var TrailFrames = []string{"universe", "barycenter", "selected"}
+
+var trailSteps int
+
+// Records the trails once every trail sampling interval
+func (g *Game) UpdateTrails() {
+	if !g.EditOpt.ShowTrails {
+		return
+	}
+	every := g.EditOpt.TrailEvery
+	if every <= 0 {
+		every = simul.DEFAULT_TRAIL_EVERY
+	}
+	if trailSteps%every == 0 {
+		g.Universe.RecordTrails(g.EditOpt.TrailLength)
+	}
+	trailSteps++
+}
+
+/*
+Returns the trail of the reference of the trail frame, nil for the universe.
+false when the reference has no trail.
+*/
+func (g *Game) GetTrailReference() (*simul.Trail, bool) {
+	var ref *simul.Trail
+	switch g.EditOpt.TrailFrame {
+	case "barycenter":
+		ref = g.Universe.CenterOfMassTrail
+	case "selected":
+		if sel := (*simul.Simulation)(g).GetSelected(); sel != nil {
+			ref = sel.Trail
+		}
+	default:
+		return nil, true
+	}
+	return ref, ref != nil && ref.Len() != 0
+}
+
+// Draws the trails as polylines fading to the oldest position
+func (g *Game) DrawTrails(screen *ebiten.Image) {
+	ref, ok := g.GetTrailReference()
+	if !ok {
+		return
+	}
+
+	for _, obj := range g.Universe.Objects {
+		t := obj.Trail
+		if t == nil || t.Len() < 2 {
+			continue
+		}
+		n := t.Len()
+		if ref != nil && ref.Len() < n {
+			n = ref.Len()
+		}
+
+		point := func(i int) (float64, float64) {
+			p := t.FromEnd(i)
+			if ref != nil {
+				p = p.Sub(ref.FromEnd(i)).Add(ref.FromEnd(0))
+			}
+			return g.PosToPx(p)
+		}
+
+		x0, y0 := point(0)
+		for i := 1; i < n; i++ {
+			x1, y1 := point(i)
+			a := uint8(255 * (n - i) / n)
+			if g.IsWrapped(t.FromEnd(i-1), t.FromEnd(i)) {
+				x0, y0 = x1, y1
+				continue
+			}
+			ebitenutil.DrawLine(screen, x0, y0, x1, y1, color.NRGBA{obj.Color.R, obj.Color.G, obj.Color.B, a})
+			x0, y0 = x1, y1
+		}
+	}
+}
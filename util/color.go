@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"image/color"
 )
 
@@ -26,3 +27,23 @@ func IntToRgbRange(c, r int) color.RGBA {
 		// A: uint8(c & 0xFF),
 	}
 }
+
+/*
+Parses colors on the "#rrggbb" or "#rrggbbaa" format
+*/
+func HexToRgb(s string) (color.RGBA, error) {
+	c := color.RGBA{A: 255}
+	var err error
+	switch len(s) {
+	case 7:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 9:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("invalid length")
+	}
+	if err != nil {
+		return c, fmt.Errorf("invalid color '%s': %v", s, err)
+	}
+	return c, nil
+}
@@ -4,14 +4,14 @@ import "math/rand"
 
 var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
-func RandString(n int) string {
+func RandString(r *rand.Rand, n int) string {
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		b[i] = letters[r.Intn(len(letters))]
 	}
 	return string(b)
 }
 
-func RandFloatRange(min, max float64) float64 {
-	return min + rand.Float64()*(max-min)
+func RandFloatRange(r *rand.Rand, min, max float64) float64 {
+	return min + r.Float64()*(max-min)
 }
@@ -0,0 +1,14 @@
+//go:build !headless
+
+package main
+
+import (
+	simul "github.com/Guilherme-De-Marchi/nbody-go/simulation"
+	"github.com/Guilherme-De-Marchi/nbody-go/ui"
+)
+
+func runWindow(simulConf *SimulConfig) error {
+	s := simul.NewSimulation(&simulConf.Universe, simulConf.RandOpt, simulConf.EditOpt, simulConf.OutputOpt)
+	s.GenerationType = simulConf.GenerationType
+	return (*ui.Game)(s).Init()
+}
@@ -0,0 +1,9 @@
+//go:build headless
+
+package main
+
+import "errors"
+
+func runWindow(simulConf *SimulConfig) error {
+	return errors.New("built without window, use 'nbody run'")
+}